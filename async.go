@@ -0,0 +1,129 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrAsyncQueueFull is reported for events tracked while the queue of an
+	// AsyncClient is full.
+	ErrAsyncQueueFull = errors.New("mixpanel: async queue is full")
+
+	// ErrAsyncClosed is reported for events tracked after an AsyncClient was
+	// closed.
+	ErrAsyncClosed = errors.New("mixpanel: async client is closed")
+)
+
+// AsyncClient tracks events in the background using a fixed number of
+// workers. Other calls are passed through to the wrapped client.
+type AsyncClient struct {
+	Mixpanel
+
+	jobs chan asyncJob
+
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type asyncJob struct {
+	ctx        context.Context
+	distinctId string
+	eventName  string
+	event      *Event
+	result     chan error
+}
+
+// NewAsyncClient returns an AsyncClient sending events with client from
+// workers goroutines. At most queueSize events wait for a free worker; a
+// workers count below 1 defaults to 1 and a negative queueSize to 0. Close
+// must be called to release the workers.
+func NewAsyncClient(client Mixpanel, workers, queueSize int) *AsyncClient {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	a := &AsyncClient{
+		Mixpanel: client,
+		jobs:     make(chan asyncJob, queueSize),
+	}
+
+	a.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go a.work()
+	}
+
+	return a
+}
+
+// TrackAsync tracks an event in the background and returns right away. The
+// returned channel receives the result of the request, i.e. nil on success,
+// and is then closed. If the queue is full the event is dropped and the
+// channel receives ErrAsyncQueueFull. Cancelling ctx aborts the request, or
+// skips it if it hasn't started yet.
+func (a *AsyncClient) TrackAsync(ctx context.Context, distinctId, eventName string, e *Event) <-chan error {
+	result := make(chan error, 1)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		result <- ErrAsyncClosed
+		close(result)
+		return result
+	}
+
+	select {
+	case a.jobs <- asyncJob{ctx: ctx, distinctId: distinctId, eventName: eventName, event: e, result: result}:
+	default:
+		result <- ErrAsyncQueueFull
+		close(result)
+	}
+
+	return result
+}
+
+// Close waits for the queued events to be sent, then closes the wrapped
+// client. Events tracked afterwards fail with ErrAsyncClosed. If ctx is
+// done first, Close returns its error while the workers keep sending the
+// queued events.
+func (a *AsyncClient) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.jobs)
+	}
+	a.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return a.Mixpanel.Close(ctx)
+}
+
+func (a *AsyncClient) work() {
+	defer a.wg.Done()
+
+	for job := range a.jobs {
+		if err := job.ctx.Err(); err != nil {
+			job.result <- err
+		} else {
+			job.result <- a.Mixpanel.Track(job.ctx, job.distinctId, job.eventName, job.event)
+		}
+		close(job.result)
+	}
+}
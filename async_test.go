@@ -0,0 +1,136 @@
+package mixpanel
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingTracker blocks Track calls until released or their context is done.
+type blockingTracker struct {
+	*Mock
+
+	mu      sync.Mutex
+	running int
+	peak    int
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingTracker() *blockingTracker {
+	return &blockingTracker{
+		Mock:    NewMock(),
+		started: make(chan struct{}, 100),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingTracker) Track(ctx context.Context, distinctId, eventName string, e *Event) error {
+	b.mu.Lock()
+	b.running++
+	if b.running > b.peak {
+		b.peak = b.running
+	}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.running--
+		b.mu.Unlock()
+	}()
+
+	b.started <- struct{}{}
+
+	select {
+	case <-b.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestTrackAsync(t *testing.T) {
+	tracker := newBlockingTracker()
+	a := NewAsyncClient(tracker, 2, 3)
+
+	results := []<-chan error{}
+	for i := 0; i < 2; i++ {
+		results = append(results, a.TrackAsync(context.TODO(), "13793", "Signed Up", &Event{}))
+	}
+	<-tracker.started
+	<-tracker.started
+
+	for i := 0; i < 3; i++ {
+		results = append(results, a.TrackAsync(context.TODO(), "13793", "Signed Up", &Event{}))
+	}
+
+	// Both workers are busy and the queue is full, the call must not block.
+	if err := <-a.TrackAsync(context.TODO(), "13793", "Signed Up", &Event{}); err != ErrAsyncQueueFull {
+		t.Errorf("TrackAsync with a full queue returned %v, want %v", err, ErrAsyncQueueFull)
+	}
+
+	close(tracker.release)
+	a.Close(context.Background())
+
+	for _, result := range results {
+		if err := <-result; err != nil {
+			t.Errorf("TrackAsync returned an error: %v", err)
+		}
+	}
+	if tracker.peak > 2 {
+		t.Errorf("%d requests ran concurrently, want at most 2", tracker.peak)
+	}
+
+	if err := <-a.TrackAsync(context.TODO(), "13793", "Signed Up", &Event{}); err != ErrAsyncClosed {
+		t.Errorf("TrackAsync after Close returned %v, want %v", err, ErrAsyncClosed)
+	}
+}
+
+func TestTrackAsyncCancel(t *testing.T) {
+	tracker := newBlockingTracker()
+	a := NewAsyncClient(tracker, 1, 1)
+	defer a.Close(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	running := a.TrackAsync(ctx, "13793", "Signed Up", &Event{})
+
+	select {
+	case <-tracker.started:
+	case <-time.After(time.Second):
+		t.Fatal("the first event was not sent")
+	}
+
+	waiting := a.TrackAsync(ctx, "13793", "Signed Up", &Event{})
+	cancel()
+
+	if err := <-running; err != context.Canceled {
+		t.Errorf("running TrackAsync returned %v, want %v", err, context.Canceled)
+	}
+	if err := <-waiting; err != context.Canceled {
+		t.Errorf("waiting TrackAsync returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAsyncCloseContext(t *testing.T) {
+	tracker := newBlockingTracker()
+	var client Mixpanel = NewAsyncClient(tracker, 1, 1)
+
+	result := client.(*AsyncClient).TrackAsync(context.TODO(), "13793", "Signed Up", &Event{})
+	<-tracker.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Close with a busy worker returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(tracker.release)
+	if err := client.Close(context.Background()); err != nil {
+		t.Errorf("Close returned an error: %v", err)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("TrackAsync returned an error: %v", err)
+	}
+}
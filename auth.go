@@ -0,0 +1,91 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ErrAmbiguousCredentials is returned by requests of a client configured
+// with both an api secret and a service account.
+var ErrAmbiguousCredentials = errors.New("mixpanel: both an api secret and a service account are configured, use only one")
+
+// ErrInvalidCredentials is returned by Validate when mixpanel rejected the
+// credentials of the client, rather than failing to answer.
+type ErrInvalidCredentials struct {
+	// Rejection reported by mixpanel, an *AuthError
+	Err error
+}
+
+func (err *ErrInvalidCredentials) Error() string {
+	return "mixpanel: invalid credentials: " + err.Err.Error()
+}
+
+func (err *ErrInvalidCredentials) Unwrap() error {
+	return err.Err
+}
+
+// hasCredentials reports whether the client can authenticate against the
+// import and query apis.
+func (m *mixpanel) hasCredentials() bool {
+	return m.Secret != "" || m.ServiceAccountUser != ""
+}
+
+// checkCredentials returns an error when the configured credentials can't
+// be used.
+func (m *mixpanel) checkCredentials() error {
+	if m.Secret != "" && m.ServiceAccountUser != "" {
+		return ErrAmbiguousCredentials
+	}
+	return nil
+}
+
+// authorize sets the authentication of request from the configured
+// credentials.
+func (m *mixpanel) authorize(request *http.Request) {
+	if m.ServiceAccountUser != "" {
+		request.SetBasicAuth(m.ServiceAccountUser, m.ServiceAccountSecret)
+	} else if m.Secret != "" {
+		request.SetBasicAuth(m.Secret, "")
+	}
+}
+
+// setProjectID adds the project id to values when authenticating with a
+// service account, which isn't tied to a single project.
+func (m *mixpanel) setProjectID(values url.Values) {
+	if m.ServiceAccountUser != "" && m.ProjectID != 0 {
+		values.Set("project_id", strconv.Itoa(m.ProjectID))
+	}
+}
+
+// Validate checks the credentials of the client by querying the profiles
+// api, without sending any data. Rejected credentials are reported as an
+// *ErrInvalidCredentials wrapped in a *MixpanelError, while requests which
+// could not be completed fail with the error of the request. Requires a
+// client created with a secret or a service account, as mixpanel accepts
+// any token.
+func (m *mixpanel) Validate(ctx context.Context) error {
+	endpoint := m.endpointURL("engage/query")
+	if !m.hasCredentials() {
+		return &MixpanelError{URL: endpoint, Err: errors.New("validating credentials requires an api secret or a service account, use WithSecret or WithServiceAccount")}
+	}
+
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	values := url.Values{"output_properties": {`["$distinct_id"]`}}
+	resp, err := m.query(ctx, "validate", http.MethodPost, endpoint, values)
+	if err != nil {
+		var merr *MixpanelError
+		if errors.As(err, &merr) && isAuthError(err) {
+			merr.Err = &ErrInvalidCredentials{Err: merr.Err}
+		}
+		return err
+	}
+
+	resp.Body.Close()
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServiceAccount(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		if r.URL.Path == "/import" {
+			w.Write([]byte(`{"code": 200, "num_records_imported": 1, "status": "OK"}`))
+		} else {
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc",
+		WithServiceAccount("sa.user", "sa-secret", 12345),
+		WithBaseURL(ts.URL),
+		WithQueryURL(ts.URL),
+	)
+
+	checkAuth := func(name string) {
+		user, pass, _ := LastRequest.BasicAuth()
+		if user != "sa.user" || pass != "sa-secret" {
+			t.Errorf("%s: basic auth returned %s:%s, want sa.user:sa-secret", name, user, pass)
+		}
+		if got := LastRequest.URL.Query().Get("project_id"); got != "12345" {
+			t.Errorf("%s: project_id returned %+v, want 12345", name, got)
+		}
+	}
+
+	if err := client.Import(context.TODO(), "13793", "Signed Up", &Event{}); err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	checkAuth("import")
+
+	if _, err := client.JQL(context.TODO(), "function main() {}", nil); err != nil {
+		t.Fatalf("JQL returned an error: %v", err)
+	}
+	checkAuth("jql")
+}
+
+func TestAmbiguousCredentials(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc",
+		WithSecret("mysecret"),
+		WithServiceAccount("sa.user", "sa-secret", 12345),
+		WithBaseURL(ts.URL),
+		WithQueryURL(ts.URL),
+	)
+
+	if err := client.Import(context.TODO(), "13793", "Signed Up", &Event{}); !errors.Is(err, ErrAmbiguousCredentials) {
+		t.Errorf("Import error should be ErrAmbiguousCredentials: %v", err)
+	}
+	if _, err := client.JQL(context.TODO(), "function main() {}", nil); !errors.Is(err, ErrAmbiguousCredentials) {
+		t.Errorf("JQL error should be ErrAmbiguousCredentials: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		if user, _, _ := r.BasicAuth(); user != "mysecret" {
+			w.WriteHeader(401)
+			w.Write([]byte(`{"error": "Invalid API secret", "status": 0}`))
+			return
+		}
+		w.Write([]byte(`{"results": [], "status": "ok"}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+	if err := client.Validate(context.TODO()); err != nil {
+		t.Errorf("Validate returned an error: %v", err)
+	}
+	if LastRequest.URL.Path != "/api/2.0/engage" {
+		t.Errorf("path returned %+v, want /api/2.0/engage", LastRequest.URL.Path)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("badsecret"), WithQueryURL(ts.URL))
+	var cerr *ErrInvalidCredentials
+	if err := client.Validate(context.TODO()); !errors.As(err, &cerr) {
+		t.Errorf("Error should be an *ErrInvalidCredentials: %v", err)
+	}
+
+	url := ts.URL
+	ts.Close()
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(url))
+	var merr *MixpanelError
+	if err := client.Validate(context.TODO()); !errors.As(err, &merr) || errors.As(err, &cerr) {
+		t.Errorf("Error should be a *MixpanelError but not an *ErrInvalidCredentials: %v", err)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithQueryURL(url))
+	if err := client.Validate(context.TODO()); !errors.As(err, &merr) {
+		t.Errorf("Validate without credentials should fail: %v", err)
+	}
+}
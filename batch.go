@@ -0,0 +1,136 @@
+package mixpanel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrSendFailed is returned by Batch.Send when some of its requests failed.
+type ErrSendFailed struct {
+	// The error of every failed operation, keyed by "track", "engage" or
+	// "groups"
+	Errors map[string]error
+}
+
+func (err *ErrSendFailed) Error() string {
+	var msgs []string
+	for _, operation := range err.operations() {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", operation, err.Errors[operation]))
+	}
+
+	return "mixpanel: batch failed, " + strings.Join(msgs, "; ")
+}
+
+func (err *ErrSendFailed) Unwrap() []error {
+	var errs []error
+	for _, operation := range err.operations() {
+		errs = append(errs, err.Errors[operation])
+	}
+
+	return errs
+}
+
+func (err *ErrSendFailed) operations() []string {
+	operations := make([]string, 0, len(err.Errors))
+	for operation := range err.Errors {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+
+	return operations
+}
+
+// Batch collects events, user updates and group updates to send them
+// together with as few requests as possible:
+//
+//	batch := mixpanel.NewBatch(client)
+//	batch.AddTrack(distinctID, "Signed Up", &mixpanel.Event{})
+//	batch.AddUpdate(distinctID, &mixpanel.Update{Operation: "$set", Properties: props})
+//	err := batch.Send(ctx)
+//
+// A Batch is safe for concurrent use.
+type Batch struct {
+	client Mixpanel
+
+	mu      sync.Mutex
+	events  []*TrackEvent
+	updates []*ProfileUpdate
+	groups  []*GroupUpdate
+}
+
+// NewBatch returns an empty batch sent with client.
+func NewBatch(client Mixpanel) *Batch {
+	return &Batch{client: client}
+}
+
+// AddTrack adds an event sent with the track api.
+func (b *Batch) AddTrack(distinctID, eventName string, e *Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events = append(b.events, &TrackEvent{DistinctID: distinctID, EventName: eventName, Event: e})
+}
+
+// AddUpdate adds an update of a user profile.
+func (b *Batch) AddUpdate(distinctID string, u *Update) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.updates = append(b.updates, &ProfileUpdate{DistinctID: distinctID, Update: u})
+}
+
+// AddGroupUpdate adds an update of a group profile.
+func (b *Batch) AddGroupUpdate(groupKey, groupID string, u *Update) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.groups = append(b.groups, &GroupUpdate{GroupKey: groupKey, GroupID: groupID, Update: u})
+}
+
+// Len returns the number of events and updates waiting to be sent.
+func (b *Batch) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.events) + len(b.updates) + len(b.groups)
+}
+
+// Send sends the collected events, then the user updates, then the group
+// updates, one batch request per api, and empties the batch. Operations
+// failing don't stop the other ones; their errors are returned as an
+// *ErrSendFailed. The Offsets of their *ErrBatchFailed count the events,
+// user updates or group updates in the order they were added.
+func (b *Batch) Send(ctx context.Context) error {
+	b.mu.Lock()
+	events, updates, groups := b.events, b.updates, b.groups
+	b.events, b.updates, b.groups = nil, nil, nil
+	b.mu.Unlock()
+
+	errs := map[string]error{}
+
+	if len(events) > 0 {
+		if err := b.client.TrackBatch(ctx, events); err != nil {
+			errs["track"] = err
+		}
+	}
+	if len(updates) > 0 {
+		if err := b.client.UpdateBatch(ctx, updates); err != nil {
+			errs["engage"] = err
+		}
+	}
+
+	if len(groups) > 0 {
+		if err := b.client.UpdateGroupBatch(ctx, "", groups); err != nil {
+			errs["groups"] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ErrSendFailed{Errors: errs}
+	}
+
+	return nil
+}
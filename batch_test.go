@@ -0,0 +1,114 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	failEngage := false
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests[r.URL.Path]++
+		if failEngage && r.URL.Path == "/engage" {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error": "invalid update", "status": 0}`))
+			return
+		}
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = New("e3bc4100330c35722740fb8c6f5abddc", ts.URL)
+
+	batch := NewBatch(client)
+	batch.AddTrack("13793", "Signed Up", &Event{})
+	batch.AddTrack("13793", "Viewed Pricing", &Event{})
+	batch.AddUpdate("13793", &Update{Operation: "$set", Properties: map[string]interface{}{"Plan": "Premium"}})
+	batch.AddGroupUpdate("company", "Acme", &Update{Operation: "$set", Properties: map[string]interface{}{"Seats": 5}})
+	batch.AddGroupUpdate("team", "Sales", &Update{Operation: "$set", Properties: map[string]interface{}{"Seats": 2}})
+	batch.AddGroupUpdate("company", "Initech", &Update{Operation: "$set", Properties: map[string]interface{}{"Seats": 3}})
+
+	if batch.Len() != 6 {
+		t.Errorf("Len returned %d, want 6", batch.Len())
+	}
+	if err := batch.Send(context.TODO()); err != nil {
+		t.Errorf("Send returned an error: %v", err)
+	}
+
+	want := map[string]int{"/track": 1, "/engage": 1, "/groups": 1}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("Send made requests %v, want %v", requests, want)
+	}
+	if batch.Len() != 0 {
+		t.Errorf("Send should empty the batch, Len returned %d", batch.Len())
+	}
+
+	failEngage = true
+	batch.AddTrack("13793", "Signed Up", &Event{})
+	batch.AddUpdate("13793", &Update{Operation: "$set", Properties: map[string]interface{}{"Plan": "Premium"}})
+
+	err := batch.Send(context.TODO())
+
+	var serr *ErrSendFailed
+	if !errors.As(err, &serr) {
+		t.Fatalf("Error should be an *ErrSendFailed: %v", err)
+	}
+	if len(serr.Errors) != 1 || serr.Errors["engage"] == nil {
+		t.Errorf("Errors returned %v, want a single engage error", serr.Errors)
+	}
+	var terr *ErrTrackFailed
+	if !errors.As(err, &terr) {
+		t.Errorf("Error should wrap an *ErrTrackFailed: %v", err)
+	}
+}
+
+func TestBatchGroupFailures(t *testing.T) {
+	requests := 0
+	var keys []string
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var updates []map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&updates)
+		for _, update := range updates {
+			keys = append(keys, update["$group_key"].(string))
+		}
+		if requests == 2 {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error": "invalid update", "status": 0}`))
+			return
+		}
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithRawJSONBody(), WithBatchLimits(BatchLimits{Group: 1}))
+
+	batch := NewBatch(client)
+	batch.AddGroupUpdate("company", "Acme", &Update{Operation: "$set", Properties: map[string]interface{}{"Seats": 5}})
+	batch.AddGroupUpdate("team", "Sales", &Update{Operation: "$set", Properties: map[string]interface{}{"Seats": 2}})
+	batch.AddGroupUpdate("company", "Initech", &Update{Operation: "$set", Properties: map[string]interface{}{"Seats": 3}})
+
+	err := batch.Send(context.TODO())
+
+	if want := []string{"company", "team", "company"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Send sent group keys %v, want %v", keys, want)
+	}
+	var serr *ErrSendFailed
+	if !errors.As(err, &serr) {
+		t.Fatalf("Error should be an *ErrSendFailed: %v", err)
+	}
+	var berr *ErrBatchFailed
+	if !errors.As(serr.Errors["groups"], &berr) || !reflect.DeepEqual(berr.Offsets, []int{1}) {
+		t.Errorf("groups error should be an *ErrBatchFailed for the second update: %v", serr.Errors["groups"])
+	}
+}
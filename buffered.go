@@ -0,0 +1,448 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrBufferFull is reported for events enqueued while the buffer of a
+	// BufferedClient is full.
+	ErrBufferFull = errors.New("mixpanel: buffer is full")
+
+	// ErrBufferClosed is reported for events enqueued after a BufferedClient
+	// was closed.
+	ErrBufferClosed = errors.New("mixpanel: buffered client is closed")
+)
+
+// BufferedOption configures optional behavior of a BufferedClient.
+type BufferedOption func(*BufferedClient)
+
+// WithBufferSize sets the maximum number of events waiting to be sent.
+// Events enqueued while the buffer is full are dropped. Defaults to ten times
+// the flush size.
+func WithBufferSize(size int) BufferedOption {
+	return func(b *BufferedClient) {
+		b.bufferSize = size
+	}
+}
+
+// WithDropHandler calls handler with every event that could not be sent,
+// along with the reason why. The handler may be called concurrently with
+// Enqueue, and must neither block nor call Enqueue itself.
+func WithDropHandler(handler func(events []*TrackEvent, err error)) BufferedOption {
+	return func(b *BufferedClient) {
+		b.onDrop = handler
+	}
+}
+
+// WithUpdateDropHandler calls handler with every profile update that could
+// not be sent, as described for WithDropHandler.
+func WithUpdateDropHandler(handler func(updates []*ProfileUpdate, err error)) BufferedOption {
+	return func(b *BufferedClient) {
+		b.onDropUpdates = handler
+	}
+}
+
+// WithUpdateCoalescing merges the profile updates enqueued between two
+// flushes when they can be applied as one, such as two $set of the same
+// profile. Only consecutive updates of a profile with the same operation
+// and settings are merged, and only when the result is the same: $set keeps
+// the last value of a property, $set_once the first one, $add sums numbers
+// and $union joins lists, while $append and $remove updates are merged
+// when they change different properties. A $set is never merged with an
+// $add, nor across another operation of the same profile. Distinct ids
+// are coalesced on their own, even when aliased to the same profile.
+func WithUpdateCoalescing() BufferedOption {
+	return func(b *BufferedClient) {
+		b.coalesce = true
+	}
+}
+
+// BufferedClient queues events and profile updates and sends them with
+// TrackBatch and UpdateBatch, either once flushSize events or updates are
+// waiting or every flushInterval, whichever comes first. Other calls are
+// passed through to the wrapped client.
+type BufferedClient struct {
+	Mixpanel
+
+	flushSize     int
+	flushInterval time.Duration
+	bufferSize    int
+	onDrop        func(events []*TrackEvent, err error)
+	onDropUpdates func(updates []*ProfileUpdate, err error)
+	coalesce      bool
+
+	mu      sync.Mutex
+	events  []*TrackEvent
+	updates []*ProfileUpdate
+	closed  bool
+
+	// Held while sending, so that events are sent in the order they were
+	// enqueued.
+	flushMu sync.Mutex
+
+	dropped uint64
+	wake    chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	// Context of the periodic flushes, cancelled when Shutdown gives up.
+	loopCtx    context.Context
+	cancelLoop context.CancelFunc
+}
+
+// NewBufferedClient returns a BufferedClient sending events with client. A
+// flushSize below 1 defaults to MaxTrackBatchSize, and a flushInterval of zero
+// or less disables periodic flushes so that events are only sent once
+// flushSize of them are waiting, or on Flush and Close. Close must be called
+// to send the remaining events and release the client.
+func NewBufferedClient(client Mixpanel, flushSize int, flushInterval time.Duration, opts ...BufferedOption) *BufferedClient {
+	if flushSize < 1 {
+		flushSize = MaxTrackBatchSize
+	}
+
+	b := &BufferedClient{
+		Mixpanel:      client,
+		flushSize:     flushSize,
+		flushInterval: flushInterval,
+		bufferSize:    10 * flushSize,
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.loopCtx, b.cancelLoop = context.WithCancel(context.Background())
+
+	b.wg.Add(1)
+	go b.loop()
+
+	return b
+}
+
+// Enqueue queues an event to be sent with the next flush. Events that can't be
+// queued are reported to the drop handler.
+func (b *BufferedClient) Enqueue(distinctId, eventName string, e *Event) {
+	event := &TrackEvent{DistinctID: distinctId, EventName: eventName, Event: e}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		b.drop([]*TrackEvent{event}, ErrBufferClosed)
+		return
+	}
+	if len(b.events)+len(b.updates) >= b.bufferSize {
+		b.drop([]*TrackEvent{event}, ErrBufferFull)
+		return
+	}
+
+	b.events = append(b.events, event)
+
+	if len(b.events) >= b.flushSize {
+		b.signal()
+	}
+}
+
+// EnqueueUpdate queues a profile update to be sent with the next flush,
+// after the queued events. Updates share the buffer of the events, and
+// those that can't be queued are reported to the update drop handler.
+func (b *BufferedClient) EnqueueUpdate(distinctId string, u *Update) {
+	update := &ProfileUpdate{DistinctID: distinctId, Update: u}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		b.dropUpdates([]*ProfileUpdate{update}, ErrBufferClosed)
+		return
+	}
+	if len(b.events)+len(b.updates) >= b.bufferSize {
+		b.dropUpdates([]*ProfileUpdate{update}, ErrBufferFull)
+		return
+	}
+
+	b.updates = append(b.updates, update)
+
+	if len(b.updates) >= b.flushSize {
+		b.signal()
+	}
+}
+
+// signal wakes the loop up for a flush, unless one is already pending.
+func (b *BufferedClient) signal() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Dropped returns the number of events and updates that could not be sent
+// so far.
+func (b *BufferedClient) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// Flush sends all queued events, then all queued updates, in batches of
+// flushSize. Events and updates that failed to send are reported to the
+// drop handlers, and their errors are returned as an *ErrBatchFailed. Only
+// the failed requests of a batch split by the wrapped client are dropped.
+// Once ctx is done, the events and updates left are dropped without being
+// sent.
+func (b *BufferedClient) Flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	events, updates := b.events, b.updates
+	b.events, b.updates = nil, nil
+	b.mu.Unlock()
+
+	trackSize, engageSize := MaxTrackBatchSize, MaxEngageBatchSize
+	if sizer, ok := b.Mixpanel.(batchSizer); ok {
+		trackSize, engageSize = sizer.batchSizes()
+	}
+
+	var errs []error
+
+	for len(events) > 0 {
+		if err := ctx.Err(); err != nil {
+			b.drop(events, err)
+			errs = append(errs, err)
+			break
+		}
+
+		n := len(events)
+		if n > b.flushSize {
+			n = b.flushSize
+		}
+
+		if err := b.Mixpanel.TrackBatch(ctx, events[:n]); err != nil {
+			for _, failure := range failedChunks(ctx, n, trackSize, err) {
+				b.drop(events[failure.start:failure.end], failure.err)
+			}
+			errs = appendBatchErrors(errs, err)
+		}
+
+		events = events[n:]
+	}
+
+	if b.coalesce {
+		updates = coalesceUpdates(updates)
+	}
+	for len(updates) > 0 {
+		if err := ctx.Err(); err != nil {
+			b.dropUpdates(updates, err)
+			errs = append(errs, err)
+			break
+		}
+
+		n := len(updates)
+		if n > b.flushSize {
+			n = b.flushSize
+		}
+
+		if err := b.Mixpanel.UpdateBatch(ctx, updates[:n]); err != nil {
+			for _, failure := range failedChunks(ctx, n, engageSize, err) {
+				b.dropUpdates(updates[failure.start:failure.end], failure.err)
+			}
+			errs = appendBatchErrors(errs, err)
+		}
+
+		updates = updates[n:]
+	}
+
+	if len(errs) > 0 {
+		return &ErrBatchFailed{Errors: errs}
+	}
+
+	return nil
+}
+
+// batchSizer is implemented by the clients of NewClient, which split
+// batches into chunks of the sizes returned for TrackBatch and UpdateBatch.
+type batchSizer interface {
+	batchSizes() (track, engage int)
+}
+
+// chunkFailure is a range of a batch which failed to send with err.
+type chunkFailure struct {
+	start, end int
+	err        error
+}
+
+// failedChunks returns the ranges of a batch of n items which failed to
+// send with err, when split into chunks of size. The chunks of an
+// *ErrBatchFailed start at its Offsets, and those not sent because ctx was
+// done run to the end of the batch. Other errors fail the whole batch.
+func failedChunks(ctx context.Context, n, size int, err error) []chunkFailure {
+	var berr *ErrBatchFailed
+	if !errors.As(err, &berr) || len(berr.Offsets) != len(berr.Errors) {
+		return []chunkFailure{{start: 0, end: n, err: err}}
+	}
+
+	var failures []chunkFailure
+	done := 0
+	for i, cerr := range berr.Errors {
+		start, end := berr.Offsets[i], berr.Offsets[i]+size
+		if cerr == ctx.Err() || end > n {
+			end = n
+		}
+		if start < done {
+			// Several errors of the same chunk, such as retries.
+			start = done
+		}
+		if start >= end {
+			continue
+		}
+		failures = append(failures, chunkFailure{start: start, end: end, err: cerr})
+		done = end
+	}
+
+	return failures
+}
+
+// appendBatchErrors appends err to errs, or its errors if it is an
+// *ErrBatchFailed.
+func appendBatchErrors(errs []error, err error) []error {
+	var berr *ErrBatchFailed
+	if errors.As(err, &berr) {
+		return append(errs, berr.Errors...)
+	}
+	return append(errs, err)
+}
+
+// Close shuts the client down like Shutdown, then closes the wrapped
+// client.
+func (b *BufferedClient) Close(ctx context.Context) error {
+	if err := b.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	return b.Mixpanel.Close(ctx)
+}
+
+// Shutdown stops the periodic flushes and sends the remaining events and
+// updates. Those enqueued afterwards are dropped. It gives up once ctx is
+// done: a flush in progress is interrupted, and the events left are dropped
+// rather than sent.
+func (b *BufferedClient) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	defer b.cancelLoop()
+
+	close(b.done)
+
+	stopped := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(stopped)
+	}()
+
+	interrupted := false
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		b.cancelLoop()
+		<-stopped
+		interrupted = true
+	}
+
+	err := b.Flush(ctx)
+	if err == nil && interrupted {
+		// The events of the interrupted flush were dropped.
+		err = ctx.Err()
+	}
+
+	return err
+}
+
+// FlushOnContext shuts the client down once ctx is done, giving the last
+// flush a grace period to complete. The returned channel receives the
+// result of Shutdown, or nil if the client was closed before ctx was done.
+//
+// Combined with signal.NotifyContext, this sends the buffered events when
+// the process is asked to terminate:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+//	defer stop()
+//
+//	buffered := mixpanel.NewBufferedClient(client, 50, 10*time.Second)
+//	flushed := buffered.FlushOnContext(ctx, 5*time.Second)
+//
+//	// Serve until SIGTERM, then wait for the buffered events.
+//	<-ctx.Done()
+//	if err := <-flushed; err != nil {
+//		log.Printf("events lost on shutdown: %v", err)
+//	}
+func (b *BufferedClient) FlushOnContext(ctx context.Context, grace time.Duration) <-chan error {
+	result := make(chan error, 1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-b.done:
+			result <- nil
+			return
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+
+		result <- b.Shutdown(shutdownCtx)
+	}()
+
+	return result
+}
+
+func (b *BufferedClient) loop() {
+	defer b.wg.Done()
+
+	// A nil channel never fires, which disables periodic flushes.
+	var tick <-chan time.Time
+	if b.flushInterval > 0 {
+		ticker := time.NewTicker(b.flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-b.wake:
+		case <-tick:
+		}
+
+		// Failures are reported to the drop handler.
+		b.Flush(b.loopCtx)
+	}
+}
+
+func (b *BufferedClient) drop(events []*TrackEvent, err error) {
+	atomic.AddUint64(&b.dropped, uint64(len(events)))
+
+	if b.onDrop != nil {
+		b.onDrop(events, err)
+	}
+}
+
+func (b *BufferedClient) dropUpdates(updates []*ProfileUpdate, err error) {
+	atomic.AddUint64(&b.dropped, uint64(len(updates)))
+
+	if b.onDropUpdates != nil {
+		b.onDropUpdates(updates, err)
+	}
+}
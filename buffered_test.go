@@ -0,0 +1,322 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRecorder records the batches sent with TrackBatch and UpdateBatch.
+type batchRecorder struct {
+	*Mock
+
+	mu      sync.Mutex
+	batches [][]*TrackEvent
+	updates [][]*ProfileUpdate
+	err     error
+}
+
+func (r *batchRecorder) UpdateBatch(ctx context.Context, updates []*ProfileUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.updates = append(r.updates, updates)
+	return r.err
+}
+
+func (r *batchRecorder) TrackBatch(ctx context.Context, events []*TrackEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches = append(r.batches, events)
+	return r.err
+}
+
+func (r *batchRecorder) sent() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, batch := range r.batches {
+		n += len(batch)
+	}
+	return n
+}
+
+func (r *batchRecorder) waitFor(t *testing.T, n int) {
+	deadline := time.Now().Add(time.Second)
+	for r.sent() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d events were sent, want %d", r.sent(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBufferedClientFlushSize(t *testing.T) {
+	recorder := &batchRecorder{Mock: NewMock()}
+	b := NewBufferedClient(recorder, 2, time.Hour)
+	defer b.Close(context.Background())
+
+	b.Enqueue("13793", "Signed Up", &Event{})
+	b.Enqueue("13793", "Logged In", &Event{})
+
+	recorder.waitFor(t, 2)
+
+	if len(recorder.batches) != 1 {
+		t.Errorf("%d batches were sent, want 1", len(recorder.batches))
+	}
+	if name := recorder.batches[0][1].EventName; name != "Logged In" {
+		t.Errorf("second event is %q, want %q", name, "Logged In")
+	}
+}
+
+func TestBufferedClientFlushInterval(t *testing.T) {
+	recorder := &batchRecorder{Mock: NewMock()}
+	b := NewBufferedClient(recorder, 100, 10*time.Millisecond)
+	defer b.Close(context.Background())
+
+	b.Enqueue("13793", "Signed Up", &Event{})
+
+	recorder.waitFor(t, 1)
+}
+
+func TestBufferedClientClose(t *testing.T) {
+	recorder := &batchRecorder{Mock: NewMock()}
+
+	var dropped []error
+	b := NewBufferedClient(recorder, 100, time.Hour, WithDropHandler(func(events []*TrackEvent, err error) {
+		dropped = append(dropped, err)
+	}))
+
+	b.Enqueue("13793", "Signed Up", &Event{})
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Errorf("Close returned an error: %v", err)
+	}
+	if n := recorder.sent(); n != 1 {
+		t.Errorf("%d events were sent, want 1", n)
+	}
+
+	b.Enqueue("13793", "Signed Up", &Event{})
+
+	if len(dropped) != 1 || dropped[0] != ErrBufferClosed {
+		t.Errorf("dropped %v, want [%v]", dropped, ErrBufferClosed)
+	}
+}
+
+func TestBufferedClientDrops(t *testing.T) {
+	recorder := &batchRecorder{Mock: NewMock(), err: errors.New("boom")}
+
+	var mu sync.Mutex
+	dropped := map[error]int{}
+	b := NewBufferedClient(recorder, 100, time.Hour, WithBufferSize(2), WithDropHandler(func(events []*TrackEvent, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped[err] += len(events)
+	}))
+
+	b.Enqueue("13793", "Signed Up", &Event{})
+	b.Enqueue("13793", "Signed Up", &Event{})
+	b.Enqueue("13793", "Signed Up", &Event{})
+
+	if n := b.Dropped(); n != 1 {
+		t.Errorf("Dropped returned %d, want 1", n)
+	}
+	if dropped[ErrBufferFull] != 1 {
+		t.Errorf("%d events were dropped because the buffer was full, want 1", dropped[ErrBufferFull])
+	}
+
+	err := b.Close(context.Background())
+
+	if !errors.Is(err, recorder.err) {
+		t.Errorf("Close returned %v, want %v", err, recorder.err)
+	}
+	if n := b.Dropped(); n != 3 {
+		t.Errorf("Dropped returned %d, want 3", n)
+	}
+	if dropped[recorder.err] != 2 {
+		t.Errorf("%d events were dropped because the send failed, want 2", dropped[recorder.err])
+	}
+}
+
+func TestBufferedClientNoFlushInterval(t *testing.T) {
+	recorder := &batchRecorder{Mock: NewMock()}
+	b := NewBufferedClient(recorder, 2, 0)
+
+	b.Enqueue("13793", "Signed Up", &Event{})
+	time.Sleep(10 * time.Millisecond)
+
+	if n := recorder.sent(); n != 0 {
+		t.Errorf("%d events were sent without a flush interval, want 0", n)
+	}
+
+	b.Enqueue("13793", "Logged In", &Event{})
+	recorder.waitFor(t, 2)
+
+	b.Enqueue("13793", "Logged Out", &Event{})
+	b.Close(context.Background())
+
+	if n := recorder.sent(); n != 3 {
+		t.Errorf("%d events were sent, want 3", n)
+	}
+}
+
+// hangingBatcher never completes TrackBatch before its context is done.
+type hangingBatcher struct {
+	*Mock
+}
+
+func (h *hangingBatcher) TrackBatch(ctx context.Context, events []*TrackEvent) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBufferedClientFlushOnContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	buffered := NewBufferedClient(&hangingBatcher{Mock: NewMock()}, 2, 0)
+	flushed := buffered.FlushOnContext(ctx, 20*time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		buffered.Enqueue("13793", "Signed Up", &Event{})
+	}
+
+	cancel()
+
+	select {
+	case err := <-flushed:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Error should wrap context.DeadlineExceeded: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("shutdown did not give up after the grace period")
+	}
+
+	if dropped := buffered.Dropped(); dropped != 4 {
+		t.Errorf("Dropped returned %d, want 4", dropped)
+	}
+}
+
+func TestBufferedClientFlushOnContextClosed(t *testing.T) {
+	recorder := &batchRecorder{Mock: NewMock()}
+	buffered := NewBufferedClient(recorder, 10, 0)
+	flushed := buffered.FlushOnContext(context.Background(), time.Second)
+
+	buffered.Enqueue("13793", "Signed Up", &Event{})
+	buffered.Close(context.Background())
+
+	if err := <-flushed; err != nil {
+		t.Errorf("FlushOnContext returned %v after Close, want nil", err)
+	}
+	if recorder.sent() != 1 {
+		t.Errorf("%d events were sent, want 1", recorder.sent())
+	}
+}
+
+func TestBufferedClientUpdates(t *testing.T) {
+	recorder := &batchRecorder{Mock: NewMock()}
+	b := NewBufferedClient(recorder, 100, time.Hour, WithUpdateCoalescing())
+
+	plan := map[string]interface{}{"Plan": "Premium"}
+	b.EnqueueUpdate("13793", &Update{Operation: "$set", Properties: plan})
+	b.EnqueueUpdate("13794", &Update{Operation: "$set", Properties: map[string]interface{}{"Plan": "Free"}})
+	b.EnqueueUpdate("13793", &Update{Operation: "$set", Properties: map[string]interface{}{"Seats": 3}})
+	b.EnqueueUpdate("13793", &Update{Operation: "$add", Properties: map[string]interface{}{"Logins": 1}})
+	b.EnqueueUpdate("13793", &Update{Operation: "$add", Properties: map[string]interface{}{"Logins": 2}})
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+
+	if len(recorder.updates) != 1 {
+		t.Fatalf("%d batches were sent, want 1", len(recorder.updates))
+	}
+
+	var got []string
+	for _, update := range recorder.updates[0] {
+		got = append(got, fmt.Sprintf("%s %s %v", update.DistinctID, update.Update.Operation, update.Update.Properties))
+	}
+	want := []string{
+		"13793 $set map[Plan:Premium Seats:3]",
+		"13794 $set map[Plan:Free]",
+		"13793 $add map[Logins:3]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sent updates %q, want %q", got, want)
+	}
+	if len(plan) != 1 {
+		t.Errorf("merging modified the properties of an enqueued update: %v", plan)
+	}
+}
+
+func TestBufferedClientPartialFailure(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 2 {
+			fmt.Fprint(w, `{"error": "boom", "status": 0}`)
+			return
+		}
+		fmt.Fprint(w, `{"error": null, "status": 1}`)
+	}))
+	defer teardown()
+
+	client := NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithBatchLimits(BatchLimits{Track: 2}))
+
+	var dropped []string
+	b := NewBufferedClient(client, 6, time.Hour, WithDropHandler(func(events []*TrackEvent, err error) {
+		for _, event := range events {
+			dropped = append(dropped, event.DistinctID)
+		}
+	}))
+
+	for i := 0; i < 6; i++ {
+		b.Enqueue(fmt.Sprint(i), "Signed Up", &Event{})
+	}
+
+	err := b.Flush(context.Background())
+
+	var berr *ErrBatchFailed
+	if !errors.As(err, &berr) || len(berr.Errors) != 1 {
+		t.Fatalf("Flush should return an *ErrBatchFailed with the error of the failed request: %v", err)
+	}
+	if errors.As(berr.Errors[0], new(*ErrBatchFailed)) {
+		t.Errorf("Flush returned nested batch errors: %v", err)
+	}
+	if want := []string{"2", "3"}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped events %v, want %v", dropped, want)
+	}
+	if n := b.Dropped(); n != 2 {
+		t.Errorf("Dropped returned %d, want 2", n)
+	}
+
+	b.Close(context.Background())
+}
+
+func TestBufferedClientUpdateFlushSize(t *testing.T) {
+	recorder := &batchRecorder{Mock: NewMock()}
+	b := NewBufferedClient(recorder, 2, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		b.EnqueueUpdate(fmt.Sprint(i), &Update{Operation: "$set", Properties: map[string]interface{}{"Plan": "Free"}})
+	}
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+
+	var sizes []int
+	for _, batch := range recorder.updates {
+		sizes = append(sizes, len(batch))
+	}
+	if want := []int{2, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("sent batches of %v updates, want %v", sizes, want)
+	}
+}
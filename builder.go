@@ -0,0 +1,92 @@
+package mixpanel
+
+import "time"
+
+// EventBuilder builds an event with chained calls:
+//
+//	b := mixpanel.NewEvent("Signed Up").Set("plan", "pro").SetTime(t)
+//	client.Track(ctx, distinctID, b.Name(), b.Build())
+type EventBuilder struct {
+	name  string
+	event Event
+}
+
+// NewEvent returns a builder of an event named name.
+func NewEvent(name string) *EventBuilder {
+	return &EventBuilder{name: name, event: Event{Properties: map[string]interface{}{}}}
+}
+
+// Set sets a custom property of the event.
+func (b *EventBuilder) Set(key string, value interface{}) *EventBuilder {
+	b.event.Properties[key] = value
+	return b
+}
+
+// SetProperties sets several custom properties of the event.
+func (b *EventBuilder) SetProperties(props map[string]interface{}) *EventBuilder {
+	for key, value := range props {
+		b.event.Properties[key] = value
+	}
+	return b
+}
+
+// SetTime sets the time of the event.
+func (b *EventBuilder) SetTime(t time.Time) *EventBuilder {
+	b.event.Timestamp = &t
+	return b
+}
+
+// SetInsertID sets the id mixpanel deduplicates the event with.
+func (b *EventBuilder) SetInsertID(id string) *EventBuilder {
+	b.event.InsertID = id
+	return b
+}
+
+// SetGroup sets the id of the group of groupKey the event belongs to.
+func (b *EventBuilder) SetGroup(groupKey string, groupID interface{}) *EventBuilder {
+	if b.event.Groups == nil {
+		b.event.Groups = map[string]interface{}{}
+	}
+	b.event.Groups[groupKey] = groupID
+	return b
+}
+
+// SetIP sets the ip-address the event is geolocated with.
+func (b *EventBuilder) SetIP(ip string) *EventBuilder {
+	b.event.IP = ip
+	return b
+}
+
+// Name returns the name of the event.
+func (b *EventBuilder) Name() string {
+	return b.name
+}
+
+// Build returns the event. The builder may be reused, without affecting the
+// events it already built.
+func (b *EventBuilder) Build() *Event {
+	e := b.event
+
+	e.Properties = make(map[string]interface{}, len(b.event.Properties))
+	for key, value := range b.event.Properties {
+		e.Properties[key] = value
+	}
+	if b.event.Groups != nil {
+		e.Groups = make(map[string]interface{}, len(b.event.Groups))
+		for key, value := range b.event.Groups {
+			e.Groups[key] = value
+		}
+	}
+	if b.event.Timestamp != nil {
+		t := *b.event.Timestamp
+		e.Timestamp = &t
+	}
+
+	return &e
+}
+
+// For returns the event of distinctID, ready to be sent with TrackBatch or
+// ImportBatch.
+func (b *EventBuilder) For(distinctID string) *TrackEvent {
+	return &TrackEvent{DistinctID: distinctID, EventName: b.name, Event: b.Build()}
+}
@@ -0,0 +1,49 @@
+package mixpanel
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventBuilder(t *testing.T) {
+	setup()
+	defer teardown()
+
+	at := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Timestamp: &at,
+		InsertID:  "abc",
+		Properties: map[string]interface{}{
+			"plan":  "pro",
+			"seats": 3,
+		},
+	})
+	manual := decodeBody()
+
+	b := NewEvent("Signed Up").Set("plan", "pro").Set("seats", 3).SetTime(at).SetInsertID("abc")
+	client.Track(context.TODO(), "13793", b.Name(), b.Build())
+
+	if built := decodeBody(); !reflect.DeepEqual(built, manual) {
+		t.Errorf("Post body returned %+v, want %+v", built, manual)
+	}
+
+	first := b.Build()
+	b.Set("plan", "team")
+	if first.Properties["plan"] != "pro" {
+		t.Errorf("built events should not change with the builder")
+	}
+
+	event := b.For("13794")
+	if event.DistinctID != "13794" || event.EventName != "Signed Up" || event.Event.Properties["plan"] != "team" {
+		t.Errorf("For returned %+v", event)
+	}
+
+	grouped := b.SetGroup("company_id", "11").Build()
+	b.SetGroup("company_id", "12")
+	if grouped.Groups["company_id"] != "11" {
+		t.Errorf("built groups should not change with the builder")
+	}
+}
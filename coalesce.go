@@ -0,0 +1,139 @@
+package mixpanel
+
+// coalesceUpdates merges the consecutive updates of each profile which can
+// be applied as a single one, keeping the order in which the updates of a
+// profile are applied. Updates of other profiles sent in between don't
+// prevent merging. Merged updates are copies, those of updates are left
+// untouched.
+func coalesceUpdates(updates []*ProfileUpdate) []*ProfileUpdate {
+	var coalesced []*ProfileUpdate
+	// Index of the last update of each profile in coalesced, and whether it
+	// is a copy which can be modified.
+	last := map[string]int{}
+	copied := map[int]bool{}
+
+	for _, update := range updates {
+		i, ok := last[update.DistinctID]
+		if ok && mergeable(coalesced[i].Update, update.Update) {
+			if !copied[i] {
+				coalesced[i] = copyProfileUpdate(coalesced[i])
+				copied[i] = true
+			}
+			mergeProperties(coalesced[i].Update, update.Update.Properties)
+			continue
+		}
+
+		last[update.DistinctID] = len(coalesced)
+		coalesced = append(coalesced, update)
+	}
+
+	return coalesced
+}
+
+// mergeable reports whether applying a then b has the same result as
+// applying a single update with the operation and the merged properties of
+// both.
+func mergeable(a, b *Update) bool {
+	if a.Operation != b.Operation || a.Token != b.Token || a.IP != b.IP || a.DisableGeolocation != b.DisableGeolocation ||
+		a.IgnoreTime != b.IgnoreTime || a.IgnoreAlias != b.IgnoreAlias || !sameTimestamp(a, b) {
+		return false
+	}
+	if a.Properties == nil || b.Properties == nil {
+		return false
+	}
+
+	switch a.Operation {
+	case "$set", "$set_once":
+		return true
+	case "$add":
+		for key, value := range b.Properties {
+			if current, ok := a.Properties[key]; ok {
+				if _, ok := addNumbers(current, value); !ok {
+					return false
+				}
+			}
+		}
+		return true
+	case "$union":
+		for key, value := range b.Properties {
+			if current, ok := a.Properties[key]; ok {
+				_, okCurrent := current.([]interface{})
+				_, okValue := value.([]interface{})
+				if !okCurrent || !okValue {
+					return false
+				}
+			}
+		}
+		return true
+	case "$append", "$remove":
+		// A property can only be given once, and the values of each
+		// property are applied in order.
+		for key := range b.Properties {
+			if _, ok := a.Properties[key]; ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func sameTimestamp(a, b *Update) bool {
+	if a.Timestamp == nil || b.Timestamp == nil {
+		return a.Timestamp == b.Timestamp
+	}
+	if a.Timestamp == IgnoreTime || b.Timestamp == IgnoreTime {
+		return a.Timestamp == b.Timestamp
+	}
+	return a.Timestamp.Equal(*b.Timestamp)
+}
+
+// mergeProperties merges props into the properties of u, which must be
+// mergeable with an update of props.
+func mergeProperties(u *Update, props map[string]interface{}) {
+	for key, value := range props {
+		current, ok := u.Properties[key]
+		if !ok {
+			u.Properties[key] = value
+			continue
+		}
+
+		switch u.Operation {
+		case "$set":
+			u.Properties[key] = value
+		case "$add":
+			u.Properties[key], _ = addNumbers(current, value)
+		case "$union":
+			u.Properties[key] = append(append([]interface{}{}, current.([]interface{})...), value.([]interface{})...)
+		}
+		// $set_once keeps the value set first.
+	}
+}
+
+// addNumbers returns the sum of a and b if they are numbers of the same
+// type.
+func addNumbers(a, b interface{}) (interface{}, bool) {
+	switch a := a.(type) {
+	case int:
+		b, ok := b.(int)
+		return a + b, ok
+	case int64:
+		b, ok := b.(int64)
+		return a + b, ok
+	case float64:
+		b, ok := b.(float64)
+		return a + b, ok
+	}
+	return nil, false
+}
+
+func copyProfileUpdate(update *ProfileUpdate) *ProfileUpdate {
+	u := *update.Update
+	u.Properties = make(map[string]interface{}, len(update.Update.Properties))
+	for key, value := range update.Update.Properties {
+		u.Properties[key] = value
+	}
+
+	return &ProfileUpdate{DistinctID: update.DistinctID, Update: &u}
+}
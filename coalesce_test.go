@@ -0,0 +1,77 @@
+package mixpanel
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoalesceUpdates(t *testing.T) {
+	at := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	update := func(operation string, props map[string]interface{}) *ProfileUpdate {
+		return &ProfileUpdate{DistinctID: "13793", Update: &Update{Operation: operation, Properties: props}}
+	}
+
+	tests := []struct {
+		name    string
+		updates []*ProfileUpdate
+		want    []*ProfileUpdate
+	}{
+		{
+			"set keeps the last value",
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Plan": "Free"}), update("$set", map[string]interface{}{"Plan": "Premium", "Seats": 3})},
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Plan": "Premium", "Seats": 3})},
+		},
+		{
+			"set once keeps the first value",
+			[]*ProfileUpdate{update("$set_once", map[string]interface{}{"Plan": "Free"}), update("$set_once", map[string]interface{}{"Plan": "Premium"})},
+			[]*ProfileUpdate{update("$set_once", map[string]interface{}{"Plan": "Free"})},
+		},
+		{
+			"add sums numbers",
+			[]*ProfileUpdate{update("$add", map[string]interface{}{"Logins": 1}), update("$add", map[string]interface{}{"Logins": 2, "Seats": 1})},
+			[]*ProfileUpdate{update("$add", map[string]interface{}{"Logins": 3, "Seats": 1})},
+		},
+		{
+			"add of different types",
+			[]*ProfileUpdate{update("$add", map[string]interface{}{"Spent": 1}), update("$add", map[string]interface{}{"Spent": 2.5})},
+			[]*ProfileUpdate{update("$add", map[string]interface{}{"Spent": 1}), update("$add", map[string]interface{}{"Spent": 2.5})},
+		},
+		{
+			"union joins lists",
+			[]*ProfileUpdate{update("$union", map[string]interface{}{"Tags": []interface{}{"a"}}), update("$union", map[string]interface{}{"Tags": []interface{}{"b"}})},
+			[]*ProfileUpdate{update("$union", map[string]interface{}{"Tags": []interface{}{"a", "b"}})},
+		},
+		{
+			"append of the same property",
+			[]*ProfileUpdate{update("$append", map[string]interface{}{"Tags": "a"}), update("$append", map[string]interface{}{"Tags": "b"})},
+			[]*ProfileUpdate{update("$append", map[string]interface{}{"Tags": "a"}), update("$append", map[string]interface{}{"Tags": "b"})},
+		},
+		{
+			"set and add",
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Logins": 0}), update("$add", map[string]interface{}{"Logins": 1})},
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Logins": 0}), update("$add", map[string]interface{}{"Logins": 1})},
+		},
+		{
+			"set across an unset",
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Plan": "Free"}), update("$unset", map[string]interface{}{"Plan": ""}), update("$set", map[string]interface{}{"Seats": 3})},
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Plan": "Free"}), update("$unset", map[string]interface{}{"Plan": ""}), update("$set", map[string]interface{}{"Seats": 3})},
+		},
+		{
+			"set of different timestamps",
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Plan": "Free"}), {DistinctID: "13793", Update: &Update{Operation: "$set", Timestamp: &at, Properties: map[string]interface{}{"Seats": 3}}}},
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Plan": "Free"}), {DistinctID: "13793", Update: &Update{Operation: "$set", Timestamp: &at, Properties: map[string]interface{}{"Seats": 3}}}},
+		},
+		{
+			"set of different profiles",
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Plan": "Free"}), {DistinctID: "13794", Update: &Update{Operation: "$set", Properties: map[string]interface{}{"Seats": 3}}}},
+			[]*ProfileUpdate{update("$set", map[string]interface{}{"Plan": "Free"}), {DistinctID: "13794", Update: &Update{Operation: "$set", Properties: map[string]interface{}{"Seats": 3}}}},
+		},
+	}
+
+	for _, test := range tests {
+		if got := coalesceUpdates(test.updates); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: coalesceUpdates returned %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package mixpanel
+
+import "context"
+
+type contextPropertiesKey struct{}
+
+// WithContextProperties returns a copy of ctx carrying props, which are
+// merged into every event tracked or imported with it, and into the $set
+// and $set_once updates of profiles. This suits request scoped properties
+// such as a trace or tenant id. Properties of the call win over the ones of
+// the context, which win over super properties. The properties of ctx
+// itself are kept unless props overrides them.
+func WithContextProperties(ctx context.Context, props map[string]interface{}) context.Context {
+	merged := map[string]interface{}{}
+	for key, value := range contextProperties(ctx) {
+		merged[key] = value
+	}
+	for key, value := range props {
+		merged[key] = value
+	}
+
+	return context.WithValue(ctx, contextPropertiesKey{}, merged)
+}
+
+// contextProperties returns the properties carried by ctx.
+func contextProperties(ctx context.Context) map[string]interface{} {
+	props, _ := ctx.Value(contextPropertiesKey{}).(map[string]interface{})
+	return props
+}
@@ -0,0 +1,52 @@
+package mixpanel
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestContextProperties(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.SetSuperProperties(map[string]interface{}{"Tenant": "default", "App Version": "1.2.0"})
+
+	ctx := WithContextProperties(context.TODO(), map[string]interface{}{"Tenant": "acme", "Trace": "abc"})
+	ctx = WithContextProperties(ctx, map[string]interface{}{"Trace": "def"})
+
+	client.Track(ctx, "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{
+			"Plan": "Premium",
+		},
+	})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"App Version\":\"1.2.0\",\"Plan\":\"Premium\",\"Tenant\":\"acme\",\"Trace\":\"def\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.UpdateUser(ctx, "13793", &Update{
+		Operation:  "$set",
+		Properties: map[string]interface{}{"Trace": "ghi"},
+	})
+
+	want = "{\"$distinct_id\":\"13793\",\"$set\":{\"Tenant\":\"acme\",\"Trace\":\"ghi\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	// Other operations are sent as is.
+	client.Increment(ctx, "13793", map[string]int{"Logins": 1})
+
+	want = "{\"$add\":{\"Logins\":1},\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
@@ -0,0 +1,114 @@
+package mixpanel
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupCache remembers the insert ids of the events sent recently, see
+// WithDedup. It is safe for concurrent use.
+type dedupCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	expires map[string]time.Time
+	// Insert ids in the order they were sent, which is also the order in
+	// which they expire.
+	order []string
+}
+
+func newDedupCache(size int, ttl time.Duration) *dedupCache {
+	return &dedupCache{size: size, ttl: ttl, expires: map[string]time.Time{}}
+}
+
+// seen reports whether an event with insertID was sent within the window
+// ending at now.
+func (c *dedupCache) seen(insertID string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(now)
+	_, ok := c.expires[insertID]
+	return ok
+}
+
+// add remembers that events with the given insert ids were sent at now.
+func (c *dedupCache) add(insertIDs []string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(now)
+	expires := now.Add(c.ttl)
+	for _, id := range insertIDs {
+		if _, ok := c.expires[id]; ok {
+			continue
+		}
+		c.expires[id] = expires
+		c.order = append(c.order, id)
+	}
+
+	for len(c.order) > c.size {
+		delete(c.expires, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// expire forgets the insert ids sent before the window ending at now.
+func (c *dedupCache) expire(now time.Time) {
+	for len(c.order) > 0 && !now.Before(c.expires[c.order[0]]) {
+		delete(c.expires, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// dedupEvents returns the events of params, track parameters of a single
+// event or a batch, which were not sent within the window of WithDedup, and
+// their insert ids. Params are returned as is without WithDedup.
+func (m *mixpanel) dedupEvents(params interface{}) (interface{}, []string) {
+	if m.dedup == nil {
+		return params, nil
+	}
+
+	switch p := params.(type) {
+	case map[string]interface{}:
+		id := insertIDOf(p)
+		if id == "" {
+			return params, nil
+		}
+		if m.dedup.seen(id, m.Clock()) {
+			m.Logger.Debugf("mixpanel: skipping event %s, sent less than %s ago", id, m.dedup.ttl)
+			return nil, nil
+		}
+		return params, []string{id}
+	case []map[string]interface{}:
+		var kept []map[string]interface{}
+		var ids []string
+		batched := map[string]bool{}
+		for _, event := range p {
+			id := insertIDOf(event)
+			if id != "" && (batched[id] || m.dedup.seen(id, m.Clock())) {
+				m.Logger.Debugf("mixpanel: skipping event %s, sent less than %s ago", id, m.dedup.ttl)
+				continue
+			}
+			if id != "" {
+				batched[id] = true
+				ids = append(ids, id)
+			}
+			kept = append(kept, event)
+		}
+		if len(kept) == 0 {
+			return nil, nil
+		}
+		return kept, ids
+	}
+
+	return params, nil
+}
+
+// insertIDOf returns the $insert_id of the track parameters of an event.
+func insertIDOf(params map[string]interface{}) string {
+	props, _ := params["properties"].(map[string]interface{})
+	id, _ := props["$insert_id"].(string)
+	return id
+}
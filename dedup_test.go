@@ -0,0 +1,74 @@
+package mixpanel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithDedup(t *testing.T) {
+	requests, fail := 0, false
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		if fail {
+			w.Write([]byte(`{"error": "some error", "status": 0}`))
+			return
+		}
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	now := time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithDedup(2, time.Minute),
+		WithClock(func() time.Time { return now }))
+
+	track := func(insertID string) error {
+		return client.Track(context.TODO(), "13793", "Signed Up", &Event{InsertID: insertID})
+	}
+
+	track("a")
+	if err := track("a"); err != nil || requests != 1 {
+		t.Errorf("a repeated event returned %v after %d requests, want no error after 1", err, requests)
+	}
+
+	requests = 0
+	client.TrackBatch(context.TODO(), []*TrackEvent{
+		{DistinctID: "13793", EventName: "Signed Up", Event: &Event{InsertID: "a"}},
+		{DistinctID: "13793", EventName: "Signed Up", Event: &Event{InsertID: "b"}},
+		{DistinctID: "13793", EventName: "Signed Up", Event: &Event{InsertID: "b"}},
+		{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}},
+	})
+	want := "[{\"event\":\"Signed Up\",\"properties\":{\"$insert_id\":\"b\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}," +
+		"{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}]"
+	if requests != 1 || decodeBody() != want {
+		t.Errorf("Post body returned %+v, want %+v", decodeBody(), want)
+	}
+
+	// The cache holds a and b, c evicts a.
+	requests = 0
+	track("c")
+	track("a")
+	if requests != 2 {
+		t.Errorf("evicted events made %d requests, want 2", requests)
+	}
+
+	requests = 0
+	now = now.Add(time.Minute)
+	track("a")
+	if requests != 1 {
+		t.Errorf("expired events made %d requests, want 1", requests)
+	}
+
+	requests, fail = 0, true
+	track("d")
+	fail = false
+	track("d")
+	if requests != 2 {
+		t.Errorf("failed events made %d requests when sent again, want 2", requests)
+	}
+}
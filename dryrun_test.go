@@ -0,0 +1,60 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	logger := &recordingLogger{}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL("http://127.0.0.1:1"), WithDryRun(), WithLogger(logger))
+
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{"Referred By": "Friend"},
+	}); err != nil {
+		t.Errorf("Track returned an error: %v", err)
+	}
+
+	result, err := client.ImportBatchResult(context.TODO(), []*ImportEvent{
+		{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}},
+		{DistinctID: "13794", EventName: "Signed Up", Event: &Event{}},
+	})
+	if err != nil {
+		t.Errorf("ImportBatchResult returned an error: %v", err)
+	}
+	if result.NumRecordsImported != 2 {
+		t.Errorf("NumRecordsImported returned %d, want 2", result.NumRecordsImported)
+	}
+
+	requests := DryRunRequests(client)
+	if len(requests) != 2 {
+		t.Fatalf("%d requests were captured, want 2", len(requests))
+	}
+
+	track := requests[0]
+	if track.URL != "http://127.0.0.1:1/track?verbose=1" {
+		t.Errorf("URL returned %+v", track.URL)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(string(track.Body), "data="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"Referred By\":\"Friend\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+	if string(data) != want {
+		t.Errorf("body returned %+v, want %+v", string(data), want)
+	}
+
+	if requests[1].Header.Get("Authorization") == "" {
+		t.Errorf("import request should be authenticated")
+	}
+
+	if len(logger.debugs) == 0 || !strings.Contains(strings.Join(logger.debugs, "\n"), "dry run POST http://127.0.0.1:1/track?verbose=1") {
+		t.Errorf("captured requests should be logged: %q", logger.debugs)
+	}
+
+	if DryRunRequests(NewClient("e3bc4100330c35722740fb8c6f5abddc")) != nil {
+		t.Errorf("DryRunRequests should return nil for other clients")
+	}
+}
@@ -0,0 +1,63 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// EncodeTrack returns the body Track would send for the event, the
+// base64 encoded "data=" form or the JSON list set by WithRawJSONBody,
+// without sending it. Events are checked and
+// completed as by Track, except for the properties of a context, see
+// WithContextProperties. Bodies are returned uncompressed.
+func (m *mixpanel) EncodeTrack(distinctID, eventName string, e *Event) (string, error) {
+	if err := m.validateEvent("track", 0, distinctID, eventName, e); err != nil {
+		return "", err
+	}
+
+	params, err := m.eventToParams(context.Background(), "track", 0, distinctID, eventName, e)
+	if err != nil {
+		return "", err
+	}
+	data, err := m.encodeForm(params, m.usesQuery(params))
+	return string(data), err
+}
+
+// EncodeImport returns the JSON body Import would send for the event,
+// without sending it, as described for EncodeTrack.
+func (m *mixpanel) EncodeImport(distinctID, eventName string, e *Event) (string, error) {
+	if err := m.validateEvent("import", 0, distinctID, eventName, e); err != nil {
+		return "", err
+	}
+
+	params, err := m.eventToParams(context.Background(), "import", 0, distinctID, eventName, e)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(params)
+	return string(data), err
+}
+
+// EncodeUpdate returns the body UpdateUser would send for the update,
+// without sending it, as described for EncodeTrack.
+func (m *mixpanel) EncodeUpdate(distinctID string, u *Update) (string, error) {
+	if err := m.validateProfile(context.Background(), 0, u.Operation, u.Properties); err != nil {
+		return "", err
+	}
+
+	params := m.engageParams(context.Background(), distinctID, u, u.Properties)
+	data, err := m.encodeForm(params, m.usesQuery(params))
+	return string(data), err
+}
+
+// EncodeGroupUpdate returns the body UpdateGroup would send for the
+// update, without sending it, as described for EncodeTrack.
+func (m *mixpanel) EncodeGroupUpdate(groupKey, groupID string, u *Update) (string, error) {
+	if err := m.validateGroupUpdate(0, u.Properties); err != nil {
+		return "", err
+	}
+
+	params := m.groupUpdateParams(groupKey, groupID, u)
+	data, err := m.encodeForm(params, m.usesQuery(params))
+	return string(data), err
+}
@@ -0,0 +1,121 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	setup()
+	defer teardown()
+
+	timestamp := time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)
+	event := &Event{Timestamp: &timestamp, Properties: map[string]interface{}{"Plan": "Premium"}}
+	update := &Update{Operation: "$set", Properties: map[string]interface{}{"Plan": "Premium"}}
+
+	tests := map[string]struct {
+		encode func() (string, error)
+		send   func() error
+	}{
+		"track": {
+			func() (string, error) { return client.EncodeTrack("13793", "Signed Up", event) },
+			func() error { return client.Track(context.TODO(), "13793", "Signed Up", event) },
+		},
+		"import": {
+			func() (string, error) { return client.EncodeImport("13793", "Signed Up", event) },
+			func() error { return client.Import(context.TODO(), "13793", "Signed Up", event) },
+		},
+		"engage": {
+			func() (string, error) { return client.EncodeUpdate("13793", update) },
+			func() error { return client.UpdateUser(context.TODO(), "13793", update) },
+		},
+		"groups": {
+			func() (string, error) { return client.EncodeGroupUpdate("company", "Acme", update) },
+			func() error { return client.UpdateGroup(context.TODO(), "company", "Acme", update) },
+		},
+	}
+
+	for name, test := range tests {
+		encoded, err := test.encode()
+		if err != nil {
+			t.Errorf("%s: encoding returned an error: %v", name, err)
+			continue
+		}
+
+		test.send()
+		if encoded != string(LastPost) {
+			t.Errorf("%s: encoding returned %q, want the sent body %q", name, encoded, LastPost)
+		}
+	}
+}
+
+// FuzzEncodeEvent checks that the properties of events are either encoded
+// as valid JSON, which decodes back to the same properties, or rejected
+// with an error.
+func FuzzEncodeEvent(f *testing.F) {
+	f.Add("Plan", "Premium", 0.5, []byte("raw"), false)
+	f.Add("", "\xff\xfe", math.NaN(), []byte(nil), true)
+	f.Add("<b>", " ", math.Inf(1), []byte{0}, true)
+	f.Add("a.b", "", -0.0, []byte("{}"), false)
+
+	m := NewClient("e3bc4100330c35722740fb8c6f5abddc").(*mixpanel)
+
+	f.Fuzz(func(t *testing.T, key, value string, number float64, raw []byte, nested bool) {
+		props := map[string]interface{}{
+			key:      value,
+			"number": number,
+			"raw":    raw,
+			"nil":    nil,
+		}
+		if nested {
+			props["nested"] = map[string]interface{}{key: []interface{}{value, number, nil}}
+		}
+		e := &Event{Properties: props}
+
+		for name, encode := range map[string]func() (string, error){
+			"import": func() (string, error) { return m.EncodeImport("13793", "Signed Up", e) },
+			"track": func() (string, error) {
+				form, err := m.EncodeTrack("13793", "Signed Up", e)
+				if err != nil {
+					return "", err
+				}
+				data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(form, "data="))
+				return string(data), err
+			},
+		} {
+			data, err := encode()
+			if math.IsNaN(number) || math.IsInf(number, 0) {
+				var verr *ValidationError
+				if !errors.As(err, &verr) || verr.Field != "properties" {
+					t.Errorf("%s: encoding %v should fail validation: %v", name, number, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: encoding %+v failed: %v", name, props, err)
+				continue
+			}
+
+			if !json.Valid([]byte(data)) {
+				t.Fatalf("%s: encoding %+v returned invalid JSON %s", name, props, data)
+			}
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+				t.Fatal(err)
+			}
+			again, err := json.Marshal(decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != data {
+				t.Errorf("%s: JSON %s doesn't round-trip, got %s", name, data, again)
+			}
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Parameters of a query of user profiles
+type EngageQuery struct {
+	// Only return profiles matching this expression. See
+	// https://developer.mixpanel.com/reference/segmentation-expressions
+	Where string
+
+	// Only return these profiles
+	DistinctIDs []string
+
+	// Only return these properties of the profiles. Leave empty to return
+	// all properties.
+	OutputProperties []string
+
+	// Continue a previous query from the given page. Use
+	// EngageResults.Next rather than setting these.
+	SessionID string
+	Page      int
+}
+
+// A user profile returned by the engage api
+type EngageProfile struct {
+	DistinctID string                 `json:"$distinct_id"`
+	Properties map[string]interface{} `json:"$properties"`
+}
+
+// A page of the profiles matching an engage query
+type EngageResults struct {
+	Profiles []EngageProfile `json:"results"`
+
+	// Total number of profiles matching the query
+	Total int `json:"total"`
+
+	Page      int    `json:"page"`
+	PageSize  int    `json:"page_size"`
+	SessionID string `json:"session_id"`
+
+	m     *mixpanel
+	query EngageQuery
+}
+
+// HasNext reports whether more profiles follow this page.
+func (r *EngageResults) HasNext() bool {
+	return r.PageSize > 0 && (r.Page+1)*r.PageSize < r.Total
+}
+
+// Next fetches the page following this one. It returns an error when this
+// page is the last one.
+func (r *EngageResults) Next(ctx context.Context) (*EngageResults, error) {
+	if !r.HasNext() {
+		return nil, errors.New("mixpanel: no more engage results")
+	}
+
+	query := r.query
+	query.SessionID = r.SessionID
+	query.Page = r.Page + 1
+
+	next, err := r.m.QueryEngage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the first page reports the total
+	if next.Total == 0 {
+		next.Total = r.Total
+	}
+
+	return next, nil
+}
+
+// QueryEngage queries user profiles. Results are paginated, use Next to
+// fetch the following pages. Requires a client created with a secret or a
+// service account. See
+// https://developer.mixpanel.com/reference/engage-query
+func (m *mixpanel) QueryEngage(ctx context.Context, params EngageQuery) (*EngageResults, error) {
+	values := url.Values{}
+	if params.Where != "" {
+		values.Set("where", params.Where)
+	}
+	if len(params.DistinctIDs) > 0 {
+		ids, err := json.Marshal(params.DistinctIDs)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("distinct_ids", string(ids))
+	}
+	if len(params.OutputProperties) > 0 {
+		properties, err := json.Marshal(params.OutputProperties)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("output_properties", string(properties))
+	}
+	if params.SessionID != "" {
+		values.Set("session_id", params.SessionID)
+		values.Set("page", strconv.Itoa(params.Page))
+	}
+
+	results := &EngageResults{m: m, query: params}
+	if err := m.queryJSON(ctx, "engage_query", http.MethodPost, m.endpointURL("engage/query"), values, results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,77 @@
+package mixpanel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestQueryEngage(t *testing.T) {
+	var pages []string
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		r.ParseForm()
+		pages = append(pages, r.PostForm.Get("session_id")+"/"+r.PostForm.Get("page"))
+
+		w.WriteHeader(200)
+		if r.PostForm.Get("page") == "" {
+			w.Write([]byte(`{"page": 0, "page_size": 1, "session_id": "1234", "status": "ok", "total": 2,
+				"results": [{"$distinct_id": "13793", "$properties": {"$name": "Bob"}}]}`))
+		} else {
+			w.Write([]byte(`{"page": 1, "page_size": 1, "session_id": "1234", "status": "ok",
+				"results": [{"$distinct_id": "13794", "$properties": {"$name": "Alice"}}]}`))
+		}
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	results, err := client.QueryEngage(context.TODO(), EngageQuery{
+		Where:            `properties["$name"] != ""`,
+		OutputProperties: []string{"$name"},
+	})
+	if err != nil {
+		t.Fatalf("QueryEngage returned an error: %v", err)
+	}
+
+	want := "/api/2.0/engage"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+	if got, want := LastRequest.PostForm.Get("output_properties"), `["$name"]`; got != want {
+		t.Errorf("output_properties returned %+v, want %+v", got, want)
+	}
+	if got, want := LastRequest.PostForm.Get("where"), `properties["$name"] != ""`; got != want {
+		t.Errorf("where returned %+v, want %+v", got, want)
+	}
+
+	wantProfiles := []EngageProfile{{DistinctID: "13793", Properties: map[string]interface{}{"$name": "Bob"}}}
+	if !reflect.DeepEqual(results.Profiles, wantProfiles) {
+		t.Errorf("profiles returned %+v, want %+v", results.Profiles, wantProfiles)
+	}
+	if results.Total != 2 || !results.HasNext() {
+		t.Errorf("first page should report 2 profiles and a next page: %+v", results)
+	}
+
+	next, err := results.Next(context.TODO())
+	if err != nil {
+		t.Fatalf("Next returned an error: %v", err)
+	}
+
+	if got, want := LastRequest.PostForm.Get("where"), `properties["$name"] != ""`; got != want {
+		t.Errorf("next page where returned %+v, want %+v", got, want)
+	}
+	if next.Profiles[0].DistinctID != "13794" || next.Total != 2 || next.HasNext() {
+		t.Errorf("second page should be the last one: %+v", next)
+	}
+
+	if _, err := next.Next(context.TODO()); err == nil {
+		t.Error("Next on the last page should return an error")
+	}
+
+	if want := []string{"/", "1234/1"}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("requested pages %+v, want %+v", pages, want)
+	}
+}
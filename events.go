@@ -0,0 +1,78 @@
+package mixpanel
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Parameters of TopEvents and EventNames
+type TopEventsParams struct {
+	// What is counted: "general" for all events, "unique" for the users
+	// sending them, or "average" for the average number of events per user.
+	// Leave empty for "general".
+	Type string
+
+	// Maximum number of events returned, mixpanel returns 100 when left
+	// zero and at most 255.
+	Limit int
+}
+
+// The most common events of today
+type TopEventsResult struct {
+	// The events, the most common first
+	Events []TopEvent
+}
+
+// An event and its count of today
+type TopEvent struct {
+	Event string `json:"event"`
+	Count int    `json:"amount"`
+	// Change of the count from yesterday, such as -0.35 for a 35% drop
+	PercentChange float64 `json:"percent_change"`
+}
+
+// TopEvents returns the most common events of today, with their counts.
+// Requires a client created with a secret or a service account. See
+// https://developer.mixpanel.com/reference/list-top-events
+func (m *mixpanel) TopEvents(ctx context.Context, params TopEventsParams) (*TopEventsResult, error) {
+	values := params.values()
+
+	var jsonBody struct {
+		Events []TopEvent `json:"events"`
+	}
+	if err := m.queryJSON(ctx, "events", http.MethodGet, m.endpointURL("events/top"), values, &jsonBody); err != nil {
+		return nil, err
+	}
+
+	return &TopEventsResult{Events: jsonBody.Events}, nil
+}
+
+// EventNames returns the names of the most common events of the last 31
+// days, the most common first. Requires a client created with a secret or a
+// service account. See
+// https://developer.mixpanel.com/reference/list-most-common-events-last-31-days
+func (m *mixpanel) EventNames(ctx context.Context, params TopEventsParams) ([]string, error) {
+	values := params.values()
+
+	var names []string
+	if err := m.queryJSON(ctx, "events", http.MethodGet, m.endpointURL("events/names"), values, &names); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+func (params TopEventsParams) values() url.Values {
+	values := url.Values{}
+	if params.Type != "" {
+		values.Set("type", params.Type)
+	} else {
+		values.Set("type", "general")
+	}
+	if params.Limit > 0 {
+		values.Set("limit", strconv.Itoa(params.Limit))
+	}
+	return values
+}
@@ -0,0 +1,74 @@
+package mixpanel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTopEvents(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`{"events": [{"amount": 2, "event": "Signed Up", "percent_change": -0.35}, {"amount": 1, "event": "Logged In", "percent_change": 0.5}], "type": "unique"}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	result, err := client.TopEvents(context.TODO(), TopEventsParams{Type: "unique", Limit: 2})
+	if err != nil {
+		t.Fatalf("TopEvents returned an error: %v", err)
+	}
+
+	want := "/api/2.0/events/top"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+	query := LastRequest.URL.Query()
+	if query.Get("type") != "unique" || query.Get("limit") != "2" {
+		t.Errorf("query returned %+v, want type=unique&limit=2", query)
+	}
+
+	wantResult := &TopEventsResult{Events: []TopEvent{
+		{Event: "Signed Up", Count: 2, PercentChange: -0.35},
+		{Event: "Logged In", Count: 1, PercentChange: 0.5},
+	}}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("TopEvents returned %+v, want %+v", result, wantResult)
+	}
+}
+
+func TestEventNames(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`["Signed Up", "Logged In"]`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	names, err := client.EventNames(context.TODO(), TopEventsParams{})
+	if err != nil {
+		t.Fatalf("EventNames returned an error: %v", err)
+	}
+
+	want := "/api/2.0/events/names"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+	query := LastRequest.URL.Query()
+	if got := query.Get("type"); got != "general" {
+		t.Errorf("type returned %+v, want general", got)
+	}
+	if _, ok := query["limit"]; ok {
+		t.Errorf("limit should not be set: %+v", query.Get("limit"))
+	}
+
+	if wantNames := []string{"Signed Up", "Logged In"}; !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("EventNames returned %+v, want %+v", names, wantNames)
+	}
+}
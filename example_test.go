@@ -23,7 +23,7 @@ func ExampleMixpanel() {
 	})
 }
 
-func ExamplePeople() {
+func ExampleMixpanel_people() {
 	client := NewWithSecret("mytoken", "myapisecret", "")
 
 	client.UpdateUser(context.TODO(), "1", &Update{
@@ -50,3 +50,15 @@ func ExamplePeople() {
 		},
 	})
 }
+
+func ExampleNewWithRegion() {
+	NewWithRegion("mytoken", RegionEU)
+}
+
+func ExampleNewClient() {
+	NewClient("mytoken",
+		WithSecret("myapisecret"),
+		WithRegion(RegionEU),
+		WithRetry(3, time.Second),
+	)
+}
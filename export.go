@@ -0,0 +1,114 @@
+package mixpanel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Parameters of an export of raw events
+type ExportParams struct {
+	// First and last day of the export, inclusive. Only the dates are used,
+	// in the timezone of the project.
+	FromDate time.Time
+	ToDate   time.Time
+
+	// Only export events with these names. Leave empty to export all
+	// events.
+	Events []string
+
+	// Only export events matching this expression. See
+	// https://developer.mixpanel.com/reference/segmentation-expressions
+	Where string
+
+	// Maximum number of events to export. Leave zero for no limit.
+	Limit int
+}
+
+// An event returned by the export api
+type ExportedEvent struct {
+	Event      string                 `json:"event"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// ExportReader reads the events of an export one at a time, as they are
+// received. It must be closed once done with.
+type ExportReader struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	url    string
+	cancel context.CancelFunc
+}
+
+// Next returns the next exported event, or io.EOF once all have been read.
+func (r *ExportReader) Next() (*ExportedEvent, error) {
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+
+		if len(line) > 0 {
+			var event ExportedEvent
+			if err := json.Unmarshal(line, &event); err != nil {
+				return nil, &MixpanelError{URL: r.url, Err: err}
+			}
+			return &event, nil
+		}
+
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, &MixpanelError{URL: r.url, Err: err}
+		}
+	}
+}
+
+// Close releases the connection of the export.
+func (r *ExportReader) Close() error {
+	defer r.cancel()
+	return r.body.Close()
+}
+
+// Export exports raw events. Events are streamed from mixpanel as they are
+// read from the returned reader, so the export is never held in memory as a
+// whole. Requires a client created with a secret or a service account.
+// See
+// https://developer.mixpanel.com/reference/raw-event-export
+func (m *mixpanel) Export(ctx context.Context, params ExportParams) (*ExportReader, error) {
+	values := url.Values{}
+	values.Set("from_date", params.FromDate.Format("2006-01-02"))
+	values.Set("to_date", params.ToDate.Format("2006-01-02"))
+	if len(params.Events) > 0 {
+		events, err := json.Marshal(params.Events)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("event", string(events))
+	}
+	if params.Where != "" {
+		values.Set("where", params.Where)
+	}
+	if params.Limit > 0 {
+		values.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	endpoint := m.endpointURL("export")
+
+	// The default timeout covers the whole export, until the reader is
+	// closed.
+	ctx, cancel := m.withDefaultTimeout(ctx)
+
+	resp, err := m.query(ctx, "export", http.MethodGet, endpoint, values)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &ExportReader{body: resp.Body, reader: bufio.NewReader(resp.Body), url: endpoint, cancel: cancel}, nil
+}
@@ -0,0 +1,99 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte("{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"time\":1457018273}}\n\n"))
+		w.Write([]byte("{\"event\":\"Logged In\",\"properties\":{\"distinct_id\":\"13793\",\"time\":1457018274}}"))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithDataURL(ts.URL))
+
+	reader, err := client.Export(context.TODO(), ExportParams{
+		FromDate: time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC),
+		ToDate:   time.Date(2016, 3, 3, 0, 0, 0, 0, time.UTC),
+		Events:   []string{"Signed Up", "Logged In"},
+		Where:    `properties["$browser"] == "Chrome"`,
+		Limit:    10,
+	})
+	if err != nil {
+		t.Fatalf("Export returned an error: %v", err)
+	}
+	defer reader.Close()
+
+	want := "/api/2.0/export"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+
+	query := LastRequest.URL.Query()
+	for key, want := range map[string]string{
+		"from_date": "2016-03-01",
+		"to_date":   "2016-03-03",
+		"event":     `["Signed Up","Logged In"]`,
+		"where":     `properties["$browser"] == "Chrome"`,
+		"limit":     "10",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s returned %+v, want %+v", key, got, want)
+		}
+	}
+
+	if user, _, _ := LastRequest.BasicAuth(); user != "mysecret" {
+		t.Errorf("basic auth user returned %+v, want %+v", user, "mysecret")
+	}
+
+	var names []string
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next returned an error: %v", err)
+		}
+		names = append(names, event.Event)
+	}
+
+	if want := []string{"Signed Up", "Logged In"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("exported events %+v, want %+v", names, want)
+	}
+}
+
+func TestExportError(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"error": "Invalid API secret"}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("badsecret"), WithDataURL(ts.URL))
+
+	_, err := client.Export(context.TODO(), ExportParams{})
+
+	var qerr *ErrQueryFailed
+	if !errors.As(err, &qerr) {
+		t.Fatalf("Error should be a *ErrQueryFailed: %v", err)
+	}
+	if qerr.HTTPCode != 401 || qerr.Message != "error=Invalid API secret; httpCode=401" {
+		t.Errorf("Wrong error: %+v", qerr)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithDataURL(ts.URL))
+	if _, err := client.Export(context.TODO(), ExportParams{}); err == nil {
+		t.Error("Export without a secret should return an error")
+	}
+}
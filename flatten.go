@@ -0,0 +1,132 @@
+package mixpanel
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options of WithFlattenProperties
+type FlattenOpts struct {
+	// Joins the keys of nested properties, "." when left blank
+	Separator string
+
+	// Number of levels of nesting flattened, all of them when zero. Deeper
+	// objects are kept as the values of the flattened keys.
+	MaxDepth int
+
+	// Flatten lists containing objects into keys holding the index of each
+	// element, as in "items.0.sku". Such lists are rejected otherwise.
+	FlattenArrays bool
+}
+
+// flattening holds the result of flattening properties.
+type flattening struct {
+	flat map[string]interface{}
+
+	// Keys of the lists of objects left as is, and keys given to several
+	// values.
+	lists      []string
+	collisions []string
+}
+
+func (f *flattening) set(key string, value interface{}) {
+	if _, ok := f.flat[key]; ok {
+		f.collisions = append(f.collisions, key)
+	}
+	f.flat[key] = value
+}
+
+// flatten returns props with nested objects flattened into keys, along with
+// the sorted keys of the lists of objects which were left as is, and the
+// sorted keys of several properties once flattened, such as "a.b" for
+// {"a.b": 1, "a": {"b": 2}}, which keep one of the values.
+func (opts *FlattenOpts) flatten(props map[string]interface{}) (map[string]interface{}, []string, []string) {
+	f := &flattening{flat: map[string]interface{}{}}
+	opts.flattenInto(f, "", props, 0)
+	sort.Strings(f.lists)
+	sort.Strings(f.collisions)
+
+	return f.flat, f.lists, f.collisions
+}
+
+func (opts *FlattenOpts) flattenInto(f *flattening, prefix string, value interface{}, depth int) {
+	separator := opts.Separator
+	if separator == "" {
+		separator = "."
+	}
+	key := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + separator + name
+	}
+	deeper := opts.MaxDepth == 0 || depth <= opts.MaxDepth
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if prefix != "" && (!deeper || len(value) == 0) {
+			f.set(prefix, value)
+			return
+		}
+		for name, nested := range value {
+			opts.flattenInto(f, key(name), nested, depth+1)
+		}
+	case []map[string]interface{}:
+		list := make([]interface{}, len(value))
+		for i, nested := range value {
+			list[i] = nested
+		}
+		opts.flattenInto(f, prefix, list, depth)
+	case []interface{}:
+		if !hasObjects(value) || !deeper {
+			f.set(prefix, value)
+			return
+		}
+		if !opts.FlattenArrays {
+			f.lists = append(f.lists, prefix)
+			f.set(prefix, value)
+			return
+		}
+		for i, nested := range value {
+			opts.flattenInto(f, key(strconv.Itoa(i)), nested, depth+1)
+		}
+	default:
+		f.set(prefix, value)
+	}
+}
+
+// hasObjects reports whether a list contains objects or lists.
+func hasObjects(list []interface{}) bool {
+	for _, value := range list {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return true
+		}
+	}
+	return false
+}
+
+// flattenEvent checks that the properties of the event at index of a batch
+// can be flattened without losing any of them, and returns them flattened.
+func (m *mixpanel) flattenEvent(index int, props map[string]interface{}) (map[string]interface{}, *ValidationError) {
+	flat, lists, collisions := m.Flatten.flatten(props)
+	switch {
+	case len(lists) > 0:
+		return nil, &ValidationError{
+			Index:      index,
+			Field:      "properties",
+			Message:    "lists of objects can't be flattened without FlattenArrays: " + strings.Join(lists, ", "),
+			Properties: lists,
+		}
+	case len(collisions) > 0:
+		return nil, &ValidationError{
+			Index:      index,
+			Field:      "properties",
+			Message:    "several properties have the same key once flattened: " + strings.Join(collisions, ", "),
+			Properties: collisions,
+		}
+	}
+
+	return flat, nil
+}
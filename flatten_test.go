@@ -0,0 +1,96 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFlattenProperties(t *testing.T) {
+	setup()
+	defer teardown()
+
+	order := map[string]interface{}{
+		"id": "1234",
+		"item": map[string]interface{}{
+			"sku":   "TSHIRT",
+			"price": map[string]interface{}{"amount": 20, "currency": "USD"},
+		},
+		"tags": []interface{}{"gift", "sale"},
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithFlattenProperties(FlattenOpts{}))
+	client.Track(context.TODO(), "13793", "Ordered", &Event{Properties: map[string]interface{}{"order": order}})
+
+	want := "{\"event\":\"Ordered\",\"properties\":{\"distinct_id\":\"13793\",\"order.id\":\"1234\",\"order.item.price.amount\":20,\"order.item.price.currency\":\"USD\",\"order.item.sku\":\"TSHIRT\",\"order.tags\":[\"gift\",\"sale\"],\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithFlattenProperties(FlattenOpts{Separator: "_", MaxDepth: 2}))
+	client.Track(context.TODO(), "13793", "Ordered", &Event{Properties: map[string]interface{}{"order": order}})
+
+	want = "{\"event\":\"Ordered\",\"properties\":{\"distinct_id\":\"13793\",\"order_id\":\"1234\",\"order_item_price\":{\"amount\":20,\"currency\":\"USD\"},\"order_item_sku\":\"TSHIRT\",\"order_tags\":[\"gift\",\"sale\"],\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestFlattenArrays(t *testing.T) {
+	setup()
+	defer teardown()
+
+	props := map[string]interface{}{
+		"items": []map[string]interface{}{{"sku": "TSHIRT"}, {"sku": "HAT"}},
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithFlattenProperties(FlattenOpts{}))
+
+	var verr *ValidationError
+	err := client.Track(context.TODO(), "13793", "Ordered", &Event{Properties: props})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"items"}) {
+		t.Errorf("Lists of objects should fail validation: %v", err)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithFlattenProperties(FlattenOpts{FlattenArrays: true}))
+	client.Track(context.TODO(), "13793", "Ordered", &Event{Properties: props})
+
+	want := "{\"event\":\"Ordered\",\"properties\":{\"distinct_id\":\"13793\",\"items.0.sku\":\"TSHIRT\",\"items.1.sku\":\"HAT\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestFlattenCollisions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithFlattenProperties(FlattenOpts{}))
+
+	var verr *ValidationError
+	LastRequest = nil
+	err := client.Track(context.TODO(), "13793", "Ordered", &Event{Properties: map[string]interface{}{
+		"order.id": "1234",
+		"order":    map[string]interface{}{"id": "5678"},
+	}})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"order.id"}) {
+		t.Errorf("Properties with the same key once flattened should fail validation: %v", err)
+	}
+	if LastRequest != nil {
+		t.Errorf("Events with colliding properties should not be sent")
+	}
+
+	client.SetSuperProperties(map[string]interface{}{"items": []map[string]interface{}{{"sku": "TSHIRT"}}})
+
+	err = client.Track(context.TODO(), "13793", "Ordered", &Event{Properties: map[string]interface{}{}})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"items"}) {
+		t.Errorf("Super properties with lists of objects should fail validation: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Parameters of a funnel query
+type FunnelParams struct {
+	// First and last day of the query, inclusive. Only the dates are used,
+	// in the timezone of the project.
+	FromDate time.Time
+	ToDate   time.Time
+
+	// Number of days users have to complete the funnel. Leave zero for the
+	// length saved with the funnel.
+	Length int
+
+	// Size of the periods the funnel is computed for: "day", "week" or
+	// "month". Leave empty for "day".
+	Unit string
+
+	// Segment the users by the value of this expression, such as
+	// `properties["Plan"]`. Leave empty for counts of all users.
+	On string
+
+	// Only count users whose events match this expression
+	Where string
+
+	// Maximum number of segments returned with On, mixpanel returns 255
+	// when left zero.
+	Limit int
+}
+
+// A step of a funnel, and the number of users who reached it
+type FunnelStep struct {
+	// Name of the event of the step
+	Event string `json:"event"`
+	// Users who reached the step
+	Count int `json:"count"`
+	// Share of the users of the previous step who reached this one
+	StepConversion float64 `json:"step_conv_ratio"`
+	// Share of the users of the first step who reached this one
+	OverallConversion float64 `json:"overall_conv_ratio"`
+	// Average number of seconds taken from the previous step, zero for the
+	// first step
+	AvgTime float64 `json:"avg_time"`
+}
+
+// The counts of a funnel over one period
+type FunnelPeriod struct {
+	// Counts of all the users
+	Steps []FunnelStep
+
+	// Users who entered the funnel, and who completed it
+	StartingAmount int
+	Completion     int
+
+	// Share of the users entering the funnel who completed it
+	ConversionRate float64
+
+	// Counts of the users of each segment, with FunnelParams.On
+	Segments map[string][]FunnelStep
+}
+
+// The counts of a funnel query
+type FunnelResult struct {
+	// The periods of the query, in order, such as "2016-09-12"
+	Dates []string
+
+	// The counts of every period, keyed by date
+	Periods map[string]*FunnelPeriod
+}
+
+// A funnel saved in the project
+type FunnelInfo struct {
+	ID   int    `json:"funnel_id"`
+	Name string `json:"name"`
+}
+
+// Funnel queries the conversion of a saved funnel, which ids are listed by
+// ListFunnels. Requires a client created with a secret or a service
+// account. See https://developer.mixpanel.com/reference/funnels-query
+func (m *mixpanel) Funnel(ctx context.Context, funnelID int, params FunnelParams) (*FunnelResult, error) {
+	endpoint := m.endpointURL("funnels")
+	if funnelID <= 0 {
+		return nil, &MixpanelError{URL: endpoint, Err: errors.New("funnel requires a funnel id")}
+	}
+
+	values := url.Values{}
+	values.Set("funnel_id", strconv.Itoa(funnelID))
+	values.Set("from_date", params.FromDate.Format("2006-01-02"))
+	values.Set("to_date", params.ToDate.Format("2006-01-02"))
+	if params.Length > 0 {
+		values.Set("length", strconv.Itoa(params.Length))
+	}
+	if params.Unit != "" {
+		values.Set("unit", params.Unit)
+	}
+	if params.On != "" {
+		values.Set("on", params.On)
+	}
+	if params.Where != "" {
+		values.Set("where", params.Where)
+	}
+	if params.Limit > 0 {
+		values.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	var jsonBody struct {
+		Meta struct {
+			Dates []string `json:"dates"`
+		} `json:"meta"`
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := m.queryJSON(ctx, "funnels", http.MethodGet, endpoint, values, &jsonBody); err != nil {
+		return nil, err
+	}
+
+	result := &FunnelResult{Dates: jsonBody.Meta.Dates, Periods: map[string]*FunnelPeriod{}}
+	for date, raw := range jsonBody.Data {
+		period, err := decodeFunnelPeriod(raw)
+		if err != nil {
+			return nil, &MixpanelError{URL: endpoint, Err: err}
+		}
+		result.Periods[date] = period
+	}
+
+	return result, nil
+}
+
+// decodeFunnelPeriod decodes the counts of a period, which are the steps and
+// their analysis without segmentation, or the steps of each segment with it.
+func decodeFunnelPeriod(raw json.RawMessage) (*FunnelPeriod, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	period := &FunnelPeriod{}
+	if _, ok := fields["steps"]; ok {
+		if err := json.Unmarshal(fields["steps"], &period.Steps); err != nil {
+			return nil, err
+		}
+	} else {
+		period.Segments = map[string][]FunnelStep{}
+		for segment, raw := range fields {
+			var steps []FunnelStep
+			if err := json.Unmarshal(raw, &steps); err != nil {
+				return nil, err
+			}
+			if segment == "$overall" {
+				period.Steps = steps
+				continue
+			}
+			period.Segments[segment] = steps
+		}
+	}
+
+	if len(period.Steps) > 0 {
+		period.StartingAmount = period.Steps[0].Count
+		period.Completion = period.Steps[len(period.Steps)-1].Count
+	}
+	if period.StartingAmount > 0 {
+		period.ConversionRate = float64(period.Completion) / float64(period.StartingAmount)
+	}
+
+	return period, nil
+}
+
+// ListFunnels lists the funnels saved in the project. Requires a client
+// created with a secret or a service account.
+func (m *mixpanel) ListFunnels(ctx context.Context) ([]FunnelInfo, error) {
+	var funnels []FunnelInfo
+	if err := m.queryJSON(ctx, "funnels", http.MethodGet, m.endpointURL("funnels/list"), url.Values{}, &funnels); err != nil {
+		return nil, err
+	}
+
+	return funnels, nil
+}
@@ -0,0 +1,136 @@
+package mixpanel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFunnel(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`{"meta": {"dates": ["2016-09-12"]}, "data": {"2016-09-12": {
+			"steps": [
+				{"count": 200, "avg_time": null, "step_conv_ratio": 1, "goal": "App Open", "overall_conv_ratio": 1, "event": "App Open"},
+				{"count": 50, "avg_time": 64, "step_conv_ratio": 0.25, "goal": "Signed Up", "overall_conv_ratio": 0.25, "event": "Signed Up"}
+			],
+			"analysis": {"completion": 50, "starting_amount": 200, "steps": 2, "worst": 1}
+		}}}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	result, err := client.Funnel(context.TODO(), 7509, FunnelParams{
+		FromDate: time.Date(2016, 9, 12, 0, 0, 0, 0, time.UTC),
+		ToDate:   time.Date(2016, 9, 18, 0, 0, 0, 0, time.UTC),
+		Unit:     "week",
+	})
+	if err != nil {
+		t.Fatalf("Funnel returned an error: %v", err)
+	}
+
+	want := "/api/2.0/funnels"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+
+	query := LastRequest.URL.Query()
+	for key, want := range map[string]string{
+		"funnel_id": "7509",
+		"from_date": "2016-09-12",
+		"to_date":   "2016-09-18",
+		"unit":      "week",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s returned %+v, want %+v", key, got, want)
+		}
+	}
+
+	wantResult := &FunnelResult{
+		Dates: []string{"2016-09-12"},
+		Periods: map[string]*FunnelPeriod{
+			"2016-09-12": {
+				Steps: []FunnelStep{
+					{Event: "App Open", Count: 200, StepConversion: 1, OverallConversion: 1},
+					{Event: "Signed Up", Count: 50, StepConversion: 0.25, OverallConversion: 0.25, AvgTime: 64},
+				},
+				StartingAmount: 200,
+				Completion:     50,
+				ConversionRate: 0.25,
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("Funnel returned %+v, want %+v", result, wantResult)
+	}
+}
+
+func TestFunnelSegments(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`{"meta": {"dates": ["2016-09-12"]}, "data": {"2016-09-12": {
+			"$overall": [{"count": 10, "event": "App Open"}, {"count": 4, "event": "Signed Up"}],
+			"Chrome": [{"count": 6, "event": "App Open"}, {"count": 3, "event": "Signed Up"}]
+		}}}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	day := time.Date(2016, 9, 12, 0, 0, 0, 0, time.UTC)
+	result, err := client.Funnel(context.TODO(), 7509, FunnelParams{FromDate: day, ToDate: day, On: `properties["$browser"]`})
+	if err != nil {
+		t.Fatalf("Funnel returned an error: %v", err)
+	}
+
+	if got, want := LastRequest.URL.Query().Get("on"), `properties["$browser"]`; got != want {
+		t.Errorf("on returned %+v, want %+v", got, want)
+	}
+
+	period := result.Periods["2016-09-12"]
+	if period == nil {
+		t.Fatalf("Funnel returned no period: %+v", result)
+	}
+	if period.ConversionRate != 0.4 {
+		t.Errorf("ConversionRate returned %+v, want 0.4", period.ConversionRate)
+	}
+	wantChrome := []FunnelStep{{Event: "App Open", Count: 6}, {Event: "Signed Up", Count: 3}}
+	if !reflect.DeepEqual(period.Segments["Chrome"], wantChrome) {
+		t.Errorf("Chrome segment returned %+v, want %+v", period.Segments["Chrome"], wantChrome)
+	}
+	if _, ok := period.Segments["$overall"]; ok {
+		t.Errorf("$overall should not be a segment")
+	}
+}
+
+func TestListFunnels(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`[{"funnel_id": 7509, "name": "Signup funnel"}, {"funnel_id": 9070, "name": "Checkout"}]`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	funnels, err := client.ListFunnels(context.TODO())
+	if err != nil {
+		t.Fatalf("ListFunnels returned an error: %v", err)
+	}
+
+	want := "/api/2.0/funnels/list"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+
+	wantFunnels := []FunnelInfo{{ID: 7509, Name: "Signup funnel"}, {ID: 9070, Name: "Checkout"}}
+	if !reflect.DeepEqual(funnels, wantFunnels) {
+		t.Errorf("ListFunnels returned %+v, want %+v", funnels, wantFunnels)
+	}
+}
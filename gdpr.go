@@ -0,0 +1,197 @@
+package mixpanel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Regulation under which data is deleted or retrieved
+type ComplianceType string
+
+const (
+	ComplianceGDPR ComplianceType = "GDPR"
+	ComplianceCCPA ComplianceType = "CCPA"
+)
+
+// Options of a deletion task
+type DeletionOpts struct {
+	// Regulation of the request, GDPR when left blank
+	ComplianceType ComplianceType
+
+	// What was disclosed to the user, only used with CCPA. One of "Data",
+	// "Categories" and "Sources".
+	DisclosureType string
+}
+
+// State of a deletion task
+type DeletionStatus struct {
+	// One of "PENDING", "STAGING", "STARTED", "SUCCESS", "FAILURE",
+	// "REVOKED", "NOT_FOUND" and "UNKNOWN"
+	Status string `json:"status"`
+}
+
+// compliance sends a request to one of the GDPR apis, about the task taskID
+// unless it is empty, and decodes the results of its response into v.
+func (m *mixpanel) compliance(ctx context.Context, operation, method, api, taskID string, params interface{}, v interface{}) (err error) {
+	endpoint := m.endpointURL(api) + url.PathEscape(taskID) + "?" + url.Values{"token": {m.Token}}.Encode()
+
+	var resp *http.Response
+	wrapErr := func(err error) error {
+		return newMixpanelError(endpoint, resp, err)
+	}
+
+	if m.ComplianceToken == "" {
+		return wrapErr(errors.New("the GDPR apis require a compliance token, use WithComplianceToken"))
+	}
+
+	var body []byte
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	ctx, end := m.Tracer.StartSpan(ctx, operation, endpoint, 0)
+	var status int
+	defer func() { end(status, err) }()
+
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return wrapErr(err)
+	}
+	if params != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Authorization", "Bearer "+m.ComplianceToken)
+	if m.UserAgent != "" {
+		request.Header.Set("User-Agent", m.UserAgent)
+	}
+
+	m.Logger.Debugf("mixpanel: %s %s", method, endpoint)
+	start := time.Now()
+	resp, err = m.roundTrip(request)
+	m.runHooks(request, resp, nil, err, 1, time.Since(start))
+	if err != nil {
+		m.Logger.Errorf("mixpanel: %s %s failed: %v", method, endpoint, err)
+		return wrapErr(err)
+	}
+	status = resp.StatusCode
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return wrapErr(m.queryFailed(method, endpoint, resp))
+	}
+	m.Logger.Debugf("mixpanel: %s %s returned %d", method, endpoint, resp.StatusCode)
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	var jsonBody struct {
+		Results json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &jsonBody); err != nil {
+		if derr := m.responseDecodeError(resp, data, err); derr != nil {
+			return wrapErr(derr)
+		}
+		return wrapErr(err)
+	}
+	if err := json.Unmarshal(jsonBody.Results, v); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
+
+// CreateDeletionTask requests the deletion of all the data of the given
+// users, and returns the id of the task tracking it. Requires a client
+// created with a compliance token. See
+// https://developer.mixpanel.com/reference/create-deletion-task
+func (m *mixpanel) CreateDeletionTask(ctx context.Context, distinctIDs []string, opts DeletionOpts) (string, error) {
+	params := map[string]interface{}{
+		"distinct_ids": distinctIDs,
+	}
+	if opts.ComplianceType != "" {
+		params["compliance_type"] = opts.ComplianceType
+	}
+	if opts.DisclosureType != "" {
+		params["disclosure_type"] = opts.DisclosureType
+	}
+
+	var results struct {
+		TaskID string `json:"task_id"`
+	}
+	if err := m.compliance(ctx, "data_deletion", http.MethodPost, "data-deletions", "", params, &results); err != nil {
+		return "", err
+	}
+
+	return results.TaskID, nil
+}
+
+// GetDeletionStatus returns the state of a deletion task. See
+// https://developer.mixpanel.com/reference/check-status-of-deletion
+func (m *mixpanel) GetDeletionStatus(ctx context.Context, taskID string) (*DeletionStatus, error) {
+	var status DeletionStatus
+	if err := m.compliance(ctx, "data_deletion_status", http.MethodGet, "data-deletions", taskID, nil, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// State of a retrieval task
+type RetrievalStatus struct {
+	// One of "PENDING", "STAGING", "STARTED", "SUCCESS", "FAILURE",
+	// "REVOKED", "NOT_FOUND" and "UNKNOWN"
+	Status string `json:"status"`
+
+	// Link to download the retrieved data from, once the task succeeded
+	DownloadURL string `json:"result"`
+
+	// Reason of the failure of the task
+	Error string `json:"error"`
+}
+
+// CreateRetrievalTask requests a copy of all the data of the given users, and
+// returns the id of the task tracking it. Requires a client created with a
+// compliance token. See
+// https://developer.mixpanel.com/reference/create-retrieval
+func (m *mixpanel) CreateRetrievalTask(ctx context.Context, distinctIDs []string) (string, error) {
+	params := map[string]interface{}{
+		"distinct_ids": distinctIDs,
+	}
+
+	var results struct {
+		TaskID string `json:"task_id"`
+	}
+	if err := m.compliance(ctx, "data_retrieval", http.MethodPost, "data-retrievals", "", params, &results); err != nil {
+		return "", err
+	}
+
+	return results.TaskID, nil
+}
+
+// GetRetrievalStatus returns the state of a retrieval task. See
+// https://developer.mixpanel.com/reference/check-status-of-retrieval
+func (m *mixpanel) GetRetrievalStatus(ctx context.Context, taskID string) (*RetrievalStatus, error) {
+	var status RetrievalStatus
+	if err := m.compliance(ctx, "data_retrieval_status", http.MethodGet, "data-retrievals", taskID, nil, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
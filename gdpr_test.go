@@ -0,0 +1,141 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCreateDeletionTask(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status": "ok", "results": {"task_id": "task-1"}}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithComplianceToken("oauth-token"), WithQueryURL(ts.URL))
+
+	taskID, err := client.CreateDeletionTask(context.TODO(), []string{"13793", "13794"}, DeletionOpts{
+		ComplianceType: ComplianceCCPA,
+		DisclosureType: "Data",
+	})
+	if err != nil {
+		t.Fatalf("CreateDeletionTask returned an error: %v", err)
+	}
+	if taskID != "task-1" {
+		t.Errorf("task id returned %+v, want %+v", taskID, "task-1")
+	}
+
+	if path := LastRequest.URL.Path; path != "/api/app/data-deletions/v3.0/" {
+		t.Errorf("path returned %+v", path)
+	}
+	if token := LastRequest.URL.Query().Get("token"); token != "e3bc4100330c35722740fb8c6f5abddc" {
+		t.Errorf("token returned %+v", token)
+	}
+	if auth := LastRequest.Header.Get("Authorization"); auth != "Bearer oauth-token" {
+		t.Errorf("Authorization returned %+v", auth)
+	}
+
+	var body map[string]interface{}
+	json.Unmarshal(LastPost, &body)
+	want := map[string]interface{}{
+		"distinct_ids":    []interface{}{"13793", "13794"},
+		"compliance_type": "CCPA",
+		"disclosure_type": "Data",
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("body returned %+v, want %+v", body, want)
+	}
+}
+
+func TestGetDeletionStatus(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status": "ok", "results": {"status": "SUCCESS"}}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithComplianceToken("oauth-token"), WithQueryURL(ts.URL))
+
+	status, err := client.GetDeletionStatus(context.TODO(), "task-1")
+	if err != nil {
+		t.Fatalf("GetDeletionStatus returned an error: %v", err)
+	}
+	if status.Status != "SUCCESS" {
+		t.Errorf("status returned %+v, want SUCCESS", status.Status)
+	}
+	if path := LastRequest.URL.Path; path != "/api/app/data-deletions/v3.0/task-1" {
+		t.Errorf("path returned %+v", path)
+	}
+}
+
+func TestRetrievalTask(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		w.WriteHeader(200)
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"status": "ok", "results": {"task_id": "task-2"}}`))
+		} else {
+			w.Write([]byte(`{"status": "ok", "results": {"status": "SUCCESS", "result": "https://example.com/data.zip"}}`))
+		}
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithComplianceToken("oauth-token"), WithQueryURL(ts.URL))
+
+	taskID, err := client.CreateRetrievalTask(context.TODO(), []string{"13793"})
+	if err != nil {
+		t.Fatalf("CreateRetrievalTask returned an error: %v", err)
+	}
+	if taskID != "task-2" {
+		t.Errorf("task id returned %+v, want %+v", taskID, "task-2")
+	}
+	if path := LastRequest.URL.Path; path != "/api/app/data-retrievals/v3.0/" {
+		t.Errorf("path returned %+v", path)
+	}
+	if body, want := string(LastPost), `{"distinct_ids":["13793"]}`; body != want {
+		t.Errorf("body returned %+v, want %+v", body, want)
+	}
+
+	status, err := client.GetRetrievalStatus(context.TODO(), taskID)
+	if err != nil {
+		t.Fatalf("GetRetrievalStatus returned an error: %v", err)
+	}
+	want := &RetrievalStatus{Status: "SUCCESS", DownloadURL: "https://example.com/data.zip"}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("status returned %+v, want %+v", status, want)
+	}
+	if path := LastRequest.URL.Path; path != "/api/app/data-retrievals/v3.0/task-2" {
+		t.Errorf("path returned %+v", path)
+	}
+}
+
+func TestComplianceErrors(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+		w.Write([]byte(`{"status": "error", "error": "invalid token"}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithComplianceToken("bad-token"), WithQueryURL(ts.URL))
+
+	_, err := client.GetDeletionStatus(context.TODO(), "task-1")
+	var qerr *ErrQueryFailed
+	if !errors.As(err, &qerr) || qerr.HTTPCode != 403 {
+		t.Errorf("Error should be a 403 *ErrQueryFailed: %v", err)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithQueryURL(ts.URL))
+	if _, err := client.CreateDeletionTask(context.TODO(), []string{"13793"}, DeletionOpts{}); err == nil {
+		t.Error("CreateDeletionTask without a compliance token should return an error")
+	}
+}
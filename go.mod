@@ -1,3 +1,3 @@
 module github.com/freshpaint-io/mixpanel
 
-go 1.19
+go 1.20
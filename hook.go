@@ -0,0 +1,37 @@
+package mixpanel
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RoundTripHook is called after every attempt at sending a request, see
+// WithHook. The attempt is 1 for the first attempt at a request and grows
+// with each retry. The response, if any, carries a copy of its body for the
+// ingestion apis, which the hook may read without affecting the client, and
+// http.NoBody for the query apis, whose responses are streamed. Hooks must
+// not modify the request.
+type RoundTripHook func(req *http.Request, resp *http.Response, err error, attempt int, elapsed time.Duration)
+
+// runHooks calls the hooks of the client after an attempt at sending req.
+func (m *mixpanel) runHooks(req *http.Request, resp *http.Response, body []byte, err error, attempt int, elapsed time.Duration) {
+	if len(m.Hooks) == 0 {
+		return
+	}
+
+	for _, hook := range m.Hooks {
+		var copied *http.Response
+		if resp != nil {
+			r := *resp
+			if body != nil {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			} else {
+				r.Body = http.NoBody
+			}
+			copied = &r
+		}
+		hook(req, copied, err, attempt, elapsed)
+	}
+}
@@ -0,0 +1,54 @@
+package mixpanel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHook(t *testing.T) {
+	var requests int32
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(`{"error": "", "status": 1}`))
+	}))
+	defer teardown()
+
+	var attempts []int
+	var statuses []int
+	var bodies []string
+	hook := func(req *http.Request, resp *http.Response, err error, attempt int, elapsed time.Duration) {
+		attempts = append(attempts, attempt)
+		statuses = append(statuses, resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		bodies = append(bodies, string(body))
+		if req.URL.Path != "/track" {
+			t.Errorf("hook request path returned %+v, want /track", req.URL.Path)
+		}
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithRetry(2, time.Millisecond), WithHook(hook))
+
+	// The hook reads the body, which mustn't keep the client from parsing it.
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{}); err != nil {
+		t.Fatalf("Track returned an error: %v", err)
+	}
+
+	if want := []int{1, 2}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("attempts returned %+v, want %+v", attempts, want)
+	}
+	if want := []int{503, 200}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses returned %+v, want %+v", statuses, want)
+	}
+	if want := []string{"", `{"error": "", "status": 1}`}; !reflect.DeepEqual(bodies, want) {
+		t.Errorf("bodies returned %+v, want %+v", bodies, want)
+	}
+}
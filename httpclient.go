@@ -0,0 +1,40 @@
+package mixpanel
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultMaxIdleConnsPerHost is the number of idle connections kept open
+	// to each mixpanel host by the transport of NewHTTPTransport. The stock
+	// transport keeps 2, which makes clients sending many requests at once
+	// open and close connections all the time.
+	DefaultMaxIdleConnsPerHost = 100
+
+	// DefaultIdleConnTimeout is how long the transport of NewHTTPTransport
+	// keeps idle connections open.
+	DefaultIdleConnTimeout = 90 * time.Second
+
+	// DefaultHTTPTimeout bounds the requests of the clients created without
+	// WithHTTPClient, including the reading of their responses, so that a
+	// host which stops answering doesn't hold them forever.
+	DefaultHTTPTimeout = 60 * time.Second
+)
+
+// defaultHTTPClient sends the requests of the clients created without
+// WithHTTPClient, sharing their connections.
+var defaultHTTPClient = &http.Client{Transport: NewHTTPTransport(), Timeout: DefaultHTTPTimeout}
+
+// NewHTTPTransport returns the transport of the clients created without
+// WithHTTPClient: a copy of http.DefaultTransport keeping
+// DefaultMaxIdleConnsPerHost idle connections to each host for
+// DefaultIdleConnTimeout. It can be modified and passed to WithHTTPClient,
+// as when adding a proxy.
+func NewHTTPTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = DefaultMaxIdleConnsPerHost
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+	return transport
+}
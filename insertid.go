@@ -0,0 +1,57 @@
+package mixpanel
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// insertIDNamespace is the UUID namespace of the ids returned by NewInsertID.
+var insertIDNamespace = [16]byte{
+	0x9b, 0x6c, 0x4e, 0x0a, 0x3f, 0x51, 0x4d, 0x8e,
+	0xa1, 0x27, 0x5c, 0xd2, 0x78, 0x04, 0xe6, 0x3b,
+}
+
+// NewInsertID returns a version 5 UUID derived from the distinct id, name and
+// timestamp of an event, for use as Event.InsertID. Sending the same event
+// again yields the same id, so that mixpanel can deduplicate it.
+func NewInsertID(distinctId, eventName string, timestamp time.Time) string {
+	h := sha1.New()
+	h.Write(insertIDNamespace[:])
+	// Separate the fields so that different events can't share a name.
+	h.Write([]byte(strconv.Itoa(len(distinctId)) + ":" + distinctId))
+	h.Write([]byte(strconv.Itoa(len(eventName)) + ":" + eventName))
+	h.Write([]byte(strconv.FormatInt(timestamp.UnixNano(), 10)))
+
+	return formatUUID(h.Sum(nil))
+}
+
+// contentInsertID returns a version 5 UUID derived from the name of an
+// event and the JSON encoding of all its properties, including distinct_id
+// and time. Map keys are encoded sorted, so that equal properties yield the
+// same id.
+func contentInsertID(eventName string, props map[string]interface{}) string {
+	data, err := json.Marshal(props)
+	if err != nil {
+		return ""
+	}
+
+	h := sha1.New()
+	h.Write(insertIDNamespace[:])
+	h.Write([]byte(strconv.Itoa(len(eventName)) + ":" + eventName))
+	h.Write(data)
+
+	return formatUUID(h.Sum(nil))
+}
+
+// formatUUID formats the first 16 bytes of a SHA-1 hash as a version 5 UUID.
+func formatUUID(sum []byte) string {
+	var uuid [16]byte
+	copy(uuid[:], sum)
+	uuid[6] = (uuid[6] & 0x0f) | 0x50
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
@@ -0,0 +1,114 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestInsertID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		InsertID: "5d958f87-542d-4c10-9422-0ed75893dc81",
+	})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"$insert_id\":\"5d958f87-542d-4c10-9422-0ed75893dc81\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestNewInsertID(t *testing.T) {
+	timestamp := time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)
+
+	id := NewInsertID("13793", "Signed Up", timestamp)
+
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("NewInsertID returned %q, which is not a version 5 UUID", id)
+	}
+
+	if other := NewInsertID("13793", "Signed Up", timestamp); other != id {
+		t.Errorf("NewInsertID is not deterministic: %q != %q", other, id)
+	}
+
+	for _, other := range []string{
+		NewInsertID("13794", "Signed Up", timestamp),
+		NewInsertID("13793", "Signed Up!", timestamp),
+		NewInsertID("13793", "Signed Up", timestamp.Add(time.Millisecond)),
+		NewInsertID("1379", "3Signed Up", timestamp),
+	} {
+		if other == id {
+			t.Errorf("NewInsertID returned %q for different events", id)
+		}
+	}
+}
+
+func TestAutoInsertID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithAutoInsertID())
+
+	timestamp := time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)
+	insertID := func(e *Event) interface{} {
+		client.Track(context.TODO(), "13793", "Signed Up", e)
+
+		var body map[string]map[string]interface{}
+		json.Unmarshal([]byte(decodeBody()), &body)
+		return body["properties"]["$insert_id"]
+	}
+
+	id := insertID(&Event{Timestamp: &timestamp, Properties: map[string]interface{}{"Plan": "Premium"}})
+	if s, _ := id.(string); !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(s) {
+		t.Errorf("$insert_id returned %v, which is not a version 5 UUID", id)
+	}
+
+	if other := insertID(&Event{Timestamp: &timestamp, Properties: map[string]interface{}{"Plan": "Premium"}}); other != id {
+		t.Errorf("$insert_id is not deterministic: %v != %v", other, id)
+	}
+	if other := insertID(&Event{Timestamp: &timestamp, Properties: map[string]interface{}{"Plan": "Free"}}); other == id {
+		t.Errorf("$insert_id returned %v for different events", id)
+	}
+	if other := insertID(&Event{InsertID: "custom"}); other != "custom" {
+		t.Errorf("$insert_id returned %v, want the id of the event", other)
+	}
+}
+
+func TestWithInsertIDFunc(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL),
+		WithInsertIDFunc(func(distinctID, eventName string, e *Event) string {
+			if order, ok := e.Properties["order_id"]; ok {
+				return fmt.Sprintf("%s-%s-%v", distinctID, eventName, order)
+			}
+			return ""
+		}))
+
+	insertID := func(e *Event) interface{} {
+		client.Track(context.TODO(), "13793", "Purchased", e)
+
+		var body map[string]map[string]interface{}
+		json.Unmarshal([]byte(decodeBody()), &body)
+		return body["properties"]["$insert_id"]
+	}
+
+	if id := insertID(&Event{Properties: map[string]interface{}{"order_id": 42}}); id != "13793-Purchased-42" {
+		t.Errorf("$insert_id returned %v, want 13793-Purchased-42", id)
+	}
+	if id := insertID(&Event{InsertID: "custom", Properties: map[string]interface{}{"order_id": 42}}); id != "custom" {
+		t.Errorf("$insert_id returned %v, want the id of the event", id)
+	}
+	if id := insertID(&Event{}); id != nil {
+		t.Errorf("$insert_id returned %v, want none", id)
+	}
+}
@@ -0,0 +1,63 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// The result of a saved Insights report
+type InsightsResult struct {
+	// When the report was computed, as returned by mixpanel
+	ComputedAt string
+
+	// The dates covered by the report
+	FromDate string
+	ToDate   string
+
+	// Names of the dimensions of the series, such as "$event"
+	Headers []string
+
+	// The values of the report. Their shape depends on the report, such as
+	// counts keyed by event then date, so they are returned undecoded to be
+	// unmarshaled into the types of the caller.
+	Series json.RawMessage
+}
+
+// Insights runs an Insights report saved in the project, given the id of
+// its bookmark found in the URL of the report. Requires a client created
+// with a secret or a service account. See
+// https://developer.mixpanel.com/reference/insights-query
+func (m *mixpanel) Insights(ctx context.Context, bookmarkID int) (*InsightsResult, error) {
+	endpoint := m.endpointURL("insights")
+	if bookmarkID <= 0 {
+		return nil, &MixpanelError{URL: endpoint, Err: errors.New("insights requires a bookmark id")}
+	}
+
+	values := url.Values{}
+	values.Set("bookmark_id", strconv.Itoa(bookmarkID))
+
+	var jsonBody struct {
+		ComputedAt string `json:"computed_at"`
+		DateRange  struct {
+			FromDate string `json:"from_date"`
+			ToDate   string `json:"to_date"`
+		} `json:"date_range"`
+		Headers []string        `json:"headers"`
+		Series  json.RawMessage `json:"series"`
+	}
+	if err := m.queryJSON(ctx, "insights", http.MethodGet, endpoint, values, &jsonBody); err != nil {
+		return nil, err
+	}
+
+	return &InsightsResult{
+		ComputedAt: jsonBody.ComputedAt,
+		FromDate:   jsonBody.DateRange.FromDate,
+		ToDate:     jsonBody.DateRange.ToDate,
+		Headers:    jsonBody.Headers,
+		Series:     jsonBody.Series,
+	}, nil
+}
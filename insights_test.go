@@ -0,0 +1,69 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestInsights(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`{"computed_at": "2020-09-21T16:35:41.252314+00:00", "date_range": {"from_date": "2020-08-31T00:00:00-07:00", "to_date": "2020-09-12T13:35:41.252314-07:00"}, "headers": ["$event"], "series": {"Logged In": {"2020-08-31T00:00:00-07:00": 9852}}}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	result, err := client.Insights(context.TODO(), 12345)
+	if err != nil {
+		t.Fatalf("Insights returned an error: %v", err)
+	}
+
+	want := "/api/2.0/insights"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+	if got := LastRequest.URL.Query().Get("bookmark_id"); got != "12345" {
+		t.Errorf("bookmark_id returned %+v, want 12345", got)
+	}
+
+	if result.ComputedAt != "2020-09-21T16:35:41.252314+00:00" || result.FromDate != "2020-08-31T00:00:00-07:00" || result.ToDate != "2020-09-12T13:35:41.252314-07:00" {
+		t.Errorf("Insights returned %+v", result)
+	}
+	if !reflect.DeepEqual(result.Headers, []string{"$event"}) {
+		t.Errorf("Headers returned %+v, want [$event]", result.Headers)
+	}
+
+	var series map[string]map[string]int
+	if err := json.Unmarshal(result.Series, &series); err != nil {
+		t.Fatal(err)
+	}
+	wantSeries := map[string]map[string]int{"Logged In": {"2020-08-31T00:00:00-07:00": 9852}}
+	if !reflect.DeepEqual(series, wantSeries) {
+		t.Errorf("Series returned %+v, want %+v", series, wantSeries)
+	}
+}
+
+func TestInsightsRequiresBookmark(t *testing.T) {
+	setup()
+	defer teardown()
+	LastRequest = nil
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	_, err := client.Insights(context.TODO(), 0)
+
+	var merr *MixpanelError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Error should be a *MixpanelError: %v", err)
+	}
+	if LastRequest != nil {
+		t.Errorf("Insights without bookmark should not send a request")
+	}
+}
@@ -0,0 +1,36 @@
+package mixpanel
+
+// Logger receives diagnostics of the requests sent by a client, see
+// WithLogger. Its methods may be called concurrently.
+type Logger interface {
+	// Debugf logs requests, responses and retries.
+	Debugf(format string, args ...interface{})
+
+	// Errorf logs requests that failed.
+	Errorf(format string, args ...interface{})
+}
+
+// WarnLogger is a Logger which also logs warnings, such as requests sent
+// differently than configured. Warnings of other loggers are logged with
+// Debugf.
+type WarnLogger interface {
+	Logger
+
+	// Warnf logs requests that were sent but not as expected.
+	Warnf(format string, args ...interface{})
+}
+
+// warnf logs a warning with logger, see WarnLogger.
+func warnf(logger Logger, format string, args ...interface{}) {
+	if logger, ok := logger.(WarnLogger); ok {
+		logger.Warnf(format, args...)
+		return
+	}
+	logger.Debugf(format, args...)
+}
+
+// nopLogger is the logger of clients created without WithLogger.
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+func (nopLogger) Errorf(format string, args ...interface{}) {}
@@ -0,0 +1,82 @@
+package mixpanel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingLogger struct {
+	mu     sync.Mutex
+	debugs []string
+	errors []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.debugs = append(l.debugs, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+type warningLogger struct {
+	recordingLogger
+	warnings []string
+}
+
+func (l *warningLogger) Warnf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+func TestLogger(t *testing.T) {
+	setup()
+	defer teardown()
+
+	logger := &recordingLogger{}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithLogger(logger))
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	want := []string{
+		"mixpanel: POST " + ts.URL + "/track?verbose=1 (attempt 1)",
+		"mixpanel: POST " + ts.URL + "/track?verbose=1 returned 200",
+	}
+	if strings.Join(logger.debugs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("debug logs returned %q, want %q", logger.debugs, want)
+	}
+	if len(logger.errors) != 0 {
+		t.Errorf("error logs returned %q, want none", logger.errors)
+	}
+}
+
+func TestLoggerErrors(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"error": "Invalid API secret", "status": 0}`))
+	}))
+	defer teardown()
+
+	logger := &recordingLogger{}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("badsecret"), WithBaseURL(ts.URL), WithQueryURL(ts.URL), WithLogger(logger))
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+	client.JQL(context.TODO(), "function main() {}", nil)
+
+	want := []string{
+		"mixpanel: POST " + ts.URL + `/track?verbose=1 returned 401: {"error": "Invalid API secret", "status": 0}`,
+		"mixpanel: POST " + ts.URL + `/api/2.0/jql returned 401: {"error": "Invalid API secret", "status": 0}`,
+	}
+	if strings.Join(logger.errors, "\n") != strings.Join(want, "\n") {
+		t.Errorf("error logs returned %q, want %q", logger.errors, want)
+	}
+}
@@ -0,0 +1,99 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ReplaceLookupTable replaces the content of a lookup table with a CSV
+// file, whose first row holds the names of the columns. The file is
+// streamed as is, so it must be no larger than what mixpanel accepts.
+// Tables are replaced before mixpanel answers, so there is nothing to poll
+// once it returned; rejected files are reported as an *ErrQueryFailed
+// wrapped in a *MixpanelError. Requires a client created with a service
+// account. See
+// https://developer.mixpanel.com/reference/replace-lookup-table
+func (m *mixpanel) ReplaceLookupTable(ctx context.Context, tableID string, csv io.Reader) (err error) {
+	query := url.Values{}
+	m.setProjectID(query)
+	endpoint := m.endpointURL("lookup-tables") + "/" + url.PathEscape(tableID)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var resp *http.Response
+	wrapErr := func(err error) error {
+		return newMixpanelError(endpoint, resp, err)
+	}
+
+	if m.ServiceAccountUser == "" {
+		return wrapErr(errors.New("lookup tables require a service account, use WithServiceAccount"))
+	}
+	if err := m.checkCredentials(); err != nil {
+		return wrapErr(err)
+	}
+
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	ctx, end := m.Tracer.StartSpan(ctx, "lookup_table", endpoint, 0)
+	var status int
+	defer func() { end(status, err) }()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, csv)
+	if err != nil {
+		return wrapErr(err)
+	}
+	request.Header.Set("Content-Type", "text/csv")
+	request.Header.Set("Accept", "application/json")
+	m.authorize(request)
+	if m.UserAgent != "" {
+		request.Header.Set("User-Agent", m.UserAgent)
+	}
+
+	m.Logger.Debugf("mixpanel: PUT %s", endpoint)
+	start := time.Now()
+	resp, err = m.roundTrip(request)
+	m.runHooks(request, resp, nil, err, 1, time.Since(start))
+	if err != nil {
+		m.Logger.Errorf("mixpanel: PUT %s failed: %v", endpoint, err)
+		return wrapErr(err)
+	}
+	status = resp.StatusCode
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return wrapErr(m.queryFailed(http.MethodPut, endpoint, resp))
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	var jsonBody struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &jsonBody); err != nil {
+		if derr := m.responseDecodeError(resp, data, err); derr != nil {
+			return wrapErr(derr)
+		}
+		return wrapErr(err)
+	}
+	if jsonBody.Status != "OK" {
+		errMsg := fmt.Sprintf("error=%s; status=%s; httpCode=%d", jsonBody.Error, jsonBody.Status, resp.StatusCode)
+		return wrapErr(&ErrQueryFailed{Message: errMsg, HTTPCode: resp.StatusCode, Body: data})
+	}
+	m.Logger.Debugf("mixpanel: PUT %s returned %d", endpoint, resp.StatusCode)
+
+	return nil
+}
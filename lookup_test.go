@@ -0,0 +1,60 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReplaceLookupTable(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"error": "lookup table not found", "status": "error"}`))
+			return
+		}
+		w.Write([]byte(`{"code": 200, "status": "OK"}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithServiceAccount("sa.user", "sa-secret", 12345))
+
+	csv := "id,name\n1,Premium\n2,Free\n"
+	if err := client.ReplaceLookupTable(context.TODO(), "plans", strings.NewReader(csv)); err != nil {
+		t.Fatalf("ReplaceLookupTable returned an error: %v", err)
+	}
+
+	if LastRequest.Method != http.MethodPut || LastRequest.URL.Path != "/lookup-tables/plans" {
+		t.Errorf("request returned %s %s, want PUT /lookup-tables/plans", LastRequest.Method, LastRequest.URL.Path)
+	}
+	if got := LastRequest.URL.Query().Get("project_id"); got != "12345" {
+		t.Errorf("project_id returned %+v, want 12345", got)
+	}
+	if got := LastRequest.Header.Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Content-Type returned %+v, want text/csv", got)
+	}
+	if user, pass, _ := LastRequest.BasicAuth(); user != "sa.user" || pass != "sa-secret" {
+		t.Errorf("basic auth returned %s:%s, want sa.user:sa-secret", user, pass)
+	}
+	if string(LastPost) != csv {
+		t.Errorf("body returned %q, want %q", LastPost, csv)
+	}
+
+	err := client.ReplaceLookupTable(context.TODO(), "missing", strings.NewReader(csv))
+	var qerr *ErrQueryFailed
+	if !errors.As(err, &qerr) || qerr.HTTPCode != 404 {
+		t.Errorf("Error should be an *ErrQueryFailed with code 404: %v", err)
+	}
+
+	client = NewWithSecret("e3bc4100330c35722740fb8c6f5abddc", "mysecret", ts.URL)
+	var merr *MixpanelError
+	if err := client.ReplaceLookupTable(context.TODO(), "plans", strings.NewReader(csv)); !errors.As(err, &merr) {
+		t.Errorf("ReplaceLookupTable without a service account should fail: %v", err)
+	}
+}
@@ -1,347 +1,1973 @@
 package mixpanel
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
-	"strings"
+	"net/url"
+	"sort"
+	"sync"
 	"time"
 )
 
 var IgnoreTime *time.Time = &time.Time{}
 
+// MaxTrackBatchSize is the maximum number of events the track api accepts in
+// a single request. Larger batches are split into several requests, see
+// WithBatchLimits.
+const MaxTrackBatchSize = 50
+
+// MaxGroupBatchSize is the maximum number of updates the groups api accepts in
+// a single request. Larger batches are split into several requests, see
+// WithBatchLimits.
+const MaxGroupBatchSize = 200
+
+// MaxEngageBatchSize is the maximum number of updates the engage api accepts
+// in a single request. Larger batches are split into several requests, see
+// WithBatchLimits.
+const MaxEngageBatchSize = 2000
+
+// MaxImportBatchSize is the maximum number of events the import api accepts in
+// a single request. Larger batches are split into several requests, see
+// WithBatchLimits.
+const MaxImportBatchSize = 2000
+
 type MixpanelError struct {
 	URL string
 	Err error
+
+	// Status code and headers of the response, when one was received
+	StatusCode int
+	Header     http.Header
+}
+
+// DefaultMaxErrorBodyBytes is the longest response body quoted in error
+// messages and logs of clients created without WithMaxErrorBodyBytes.
+const DefaultMaxErrorBodyBytes = 2048
+
+// truncateBody returns body as quoted in error messages and logs.
+func (m *mixpanel) truncateBody(body []byte) string {
+	if m.MaxErrorBodyBytes > 0 && len(body) > m.MaxErrorBodyBytes {
+		return string(body[:m.MaxErrorBodyBytes]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+// newMixpanelError wraps err, returned for the request to url which
+// received resp, if any.
+func newMixpanelError(url string, resp *http.Response, err error) *MixpanelError {
+	merr := &MixpanelError{URL: url, Err: err}
+	if resp != nil {
+		merr.StatusCode = resp.StatusCode
+		merr.Header = resp.Header
+	}
+	return merr
 }
 
 func (err *MixpanelError) Cause() error {
 	return err.Err
 }
 
-func (err *MixpanelError) Error() string {
-	return "mixpanel: " + err.Err.Error()
+func (err *MixpanelError) Error() string {
+	return "mixpanel: " + err.Err.Error()
+}
+
+func (err *MixpanelError) Unwrap() error {
+	if err == nil {
+		return nil
+	}
+
+	return err.Err
+}
+
+type ErrTrackFailed struct {
+	Message  string
+	Body     []byte
+	HTTPCode int
+}
+
+func (err *ErrTrackFailed) Error() string {
+	return fmt.Sprintf("mixpanel did not return 1 when tracking: %s", err.Message)
+}
+
+// ResponseDecodeError is returned for responses whose body is not JSON at
+// all, such as the HTML page of a proxy or firewall intercepting requests,
+// rather than an answer of mixpanel. Failed responses are still wrapped in
+// an *AuthError, *BadRequestError or *ServerError according to their status
+// code.
+type ResponseDecodeError struct {
+	// Content-Type header of the response
+	ContentType string
+
+	// Start of the body, truncated as set by WithMaxErrorBodyBytes
+	Body string
+
+	Err error
+}
+
+func (err *ResponseDecodeError) Error() string {
+	return fmt.Sprintf("can't decode the response of type %q: %v, body=%s", err.ContentType, err.Err, err.Body)
+}
+
+func (err *ResponseDecodeError) Unwrap() error {
+	return err.Err
+}
+
+// responseDecodeError returns the error reporting resp when err, returned
+// by decoding body, shows that body is not JSON, and nil otherwise. Rate
+// limited responses are left to responseError, which reports their delay.
+func (m *mixpanel) responseDecodeError(resp *http.Response, body []byte, err error) error {
+	var serr *json.SyntaxError
+	if !errors.As(err, &serr) || resp.StatusCode == http.StatusTooManyRequests {
+		return nil
+	}
+
+	derr := &ResponseDecodeError{ContentType: resp.Header.Get("Content-Type"), Body: m.truncateBody(body), Err: err}
+	return classifyError(resp.StatusCode, derr)
+}
+
+// RateLimitError is returned when mixpanel rejected a request because the
+// project exceeded its rate limit.
+type RateLimitError struct {
+	// The delay suggested by mixpanel before sending the next request, or
+	// zero if it didn't suggest one.
+	RetryAfter time.Duration
+
+	Err *ErrTrackFailed
+}
+
+func (err *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s: %s", err.RetryAfter, err.Err.Error())
+}
+
+func (err *RateLimitError) Unwrap() error {
+	return err.Err
+}
+
+// AuthError is returned when mixpanel rejected the credentials of a
+// request, with a 401 or 403 status.
+type AuthError struct {
+	// The *ErrTrackFailed or *ErrQueryFailed describing the response
+	Err error
+}
+
+func (err *AuthError) Error() string {
+	return "authentication failed: " + err.Err.Error()
+}
+
+func (err *AuthError) Unwrap() error {
+	return err.Err
+}
+
+// BadRequestError is returned when mixpanel rejected a request with a 4xx
+// status other than the ones of AuthError and RateLimitError.
+type BadRequestError struct {
+	// The *ErrTrackFailed or *ErrQueryFailed describing the response
+	Err error
+}
+
+func (err *BadRequestError) Error() string {
+	return "bad request: " + err.Err.Error()
+}
+
+func (err *BadRequestError) Unwrap() error {
+	return err.Err
+}
+
+// ServerError is returned when mixpanel failed to handle a request, with a
+// 5xx status.
+type ServerError struct {
+	// The *ErrTrackFailed or *ErrQueryFailed describing the response
+	Err error
+}
+
+func (err *ServerError) Error() string {
+	return "server error: " + err.Err.Error()
+}
+
+func (err *ServerError) Unwrap() error {
+	return err.Err
+}
+
+// responseError returns the error describing a failed response.
+func (m *mixpanel) responseError(resp *http.Response, message string, body []byte) error {
+	err := &ErrTrackFailed{Message: message, HTTPCode: resp.StatusCode, Body: body}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		delay, _ := retryAfter(resp, m.Clock())
+		return &RateLimitError{RetryAfter: delay, Err: err}
+	}
+
+	return classifyError(resp.StatusCode, err)
+}
+
+// classifyError wraps the error describing a failed response according to
+// its status code. Errors reported with other status codes, such as the
+// ones of the track api answered with a 200 status, are returned as is.
+func classifyError(statusCode int, err error) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return &AuthError{Err: err}
+	case statusCode >= 400 && statusCode < 500:
+		return &BadRequestError{Err: err}
+	case statusCode >= 500:
+		return &ServerError{Err: err}
+	}
+
+	return err
+}
+
+// The outcome of an import request, as reported by mixpanel
+type ImportResult struct {
+	// Number of events that were imported.
+	NumRecordsImported int
+
+	// Events that were rejected.
+	Failed []RecordError
+
+	// Malformed lines skipped by ImportStream, see WithSkipMalformedLines
+	Skipped int
+}
+
+// RecordError describes why mixpanel rejected an event of an import request.
+type RecordError struct {
+	// Position of the event in the imported batch.
+	Index int `json:"index"`
+
+	InsertID string `json:"$insert_id"`
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+}
+
+// ErrImportFailed is returned by the import methods of clients created with
+// WithVerboseImport when some of the events were rejected.
+type ErrImportFailed struct {
+	Result ImportResult
+	Err    error
+}
+
+func (err *ErrImportFailed) Error() string {
+	return fmt.Sprintf("%d event(s) failed to import: %s", len(err.Result.Failed), err.Err.Error())
+}
+
+func (err *ErrImportFailed) Unwrap() error {
+	return err.Err
+}
+
+// ErrBatchFailed is returned by the batch methods when one or more of the
+// requests they sent failed. errors.Is and errors.As look through the errors
+// of all of them.
+type ErrBatchFailed struct {
+	// The error of every failed request, in the order of their chunks.
+	Errors []error
+
+	// Position in the batch of the first item of the chunk of every error,
+	// when known
+	Offsets []int
+}
+
+func (err *ErrBatchFailed) Error() string {
+	return fmt.Sprintf("mixpanel: %d batch request(s) failed, first error: %s", len(err.Errors), err.Errors[0])
+}
+
+func (err *ErrBatchFailed) Unwrap() []error {
+	return err.Errors
+}
+
+func isAuthError(err error) bool {
+	var aerr *AuthError
+	return errors.As(err, &aerr)
+}
+
+// isTooLarge reports whether err is a request rejected because of the size
+// of its body.
+func isTooLarge(err error) bool {
+	var terr *ErrTrackFailed
+	return errors.As(err, &terr) && terr.HTTPCode == http.StatusRequestEntityTooLarge
+}
+
+// ErrPayloadTooLarge is reported by ImportBatch for events which mixpanel
+// rejected as too large, even once split into the smallest chunks allowed
+// by WithMinImportChunkSize.
+type ErrPayloadTooLarge struct {
+	// Position of the first event in the imported batch
+	Index int
+
+	Events []*ImportEvent
+	Err    error
+}
+
+func (err *ErrPayloadTooLarge) Error() string {
+	return fmt.Sprintf("mixpanel: %d events from index %d are too large to import: %v", len(err.Events), err.Index, err.Err)
+}
+
+func (err *ErrPayloadTooLarge) Unwrap() error {
+	return err.Err
+}
+
+// The Mixapanel struct store the mixpanel endpoint and the project token
+type Mixpanel interface {
+	// Create a mixpanel event using the track api
+	Track(ctx context.Context, distinctId, eventName string, e *Event) error
+
+	// Create a mixpanel event with the properties of a struct
+	TrackStruct(ctx context.Context, distinctID, eventName string, props interface{}) error
+
+	// Create a mixpanel event checked against its registered schema
+	TrackTyped(ctx context.Context, distinctID, eventName string, e *Event) error
+
+	// Set properties merged into every event
+	SetSuperProperties(props map[string]interface{})
+
+	// Create a mixpanel event using the import api
+	Import(ctx context.Context, distinctId, eventName string, e *Event) error
+
+	// Create a batch of mixpanel events using the track api
+	TrackBatch(ctx context.Context, events []*TrackEvent) error
+
+	// Create a batch of mixpanel events using the import api
+	ImportBatch(ctx context.Context, events []*ImportEvent) error
+
+	// Import a batch of events, at the given time unless they have their own
+	ImportBatchAt(ctx context.Context, events []*ImportEvent, at time.Time) error
+
+	// Create a batch of mixpanel events using the import api, and report
+	// which ones were imported
+	ImportBatchResult(ctx context.Context, events []*ImportEvent) (*ImportResult, error)
+
+	// Import the events of a stream of newline-delimited JSON
+	ImportStream(ctx context.Context, r io.Reader) (*ImportResult, error)
+
+	// Set properties for a mixpanel user.
+	// Deprecated: Use UpdateUser instead
+	Update(ctx context.Context, distinctId string, u *Update) error
+
+	// Set properties for a mixpanel user.
+	UpdateUser(ctx context.Context, distinctId string, u *Update) error
+
+	// Update several user profiles, each with its own operation
+	UpdateBatch(ctx context.Context, updates []*ProfileUpdate) error
+
+	// Set properties for a mixpanel user, unless they are already set.
+	SetOnce(ctx context.Context, distinctId string, props map[string]interface{}) error
+
+	// Set the reserved properties of a user
+	SetProfile(ctx context.Context, distinctID string, p Profile, extra map[string]interface{}) error
+
+	// Set the coordinates of a user
+	SetLocation(ctx context.Context, distinctID string, lat, lng float64) error
+
+	// Append values to list properties of a mixpanel user.
+	Append(ctx context.Context, distinctId string, props map[string]interface{}) error
+
+	// Append a charge to the transactions of a mixpanel user.
+	TrackCharge(ctx context.Context, distinctID string, amount float64, at time.Time, props map[string]interface{}) error
+
+	// Merge values into list properties of a mixpanel user, without duplicates.
+	Union(ctx context.Context, distinctId string, props map[string][]interface{}) error
+
+	// Remove values from list properties of a mixpanel user.
+	Remove(ctx context.Context, distinctId string, props map[string]interface{}) error
+
+	// Delete properties of a mixpanel user.
+	Unset(ctx context.Context, distinctId string, properties []string) error
+
+	// Delete a mixpanel user profile.
+	DeleteProfile(ctx context.Context, distinctId string) error
+
+	// Delete a mixpanel user profile, optionally without resolving aliases.
+	DeleteProfileWithFlag(ctx context.Context, distinctId string, ignoreAlias bool) error
+
+	// Increment numeric properties of a mixpanel user.
+	Increment(ctx context.Context, distinctId string, props map[string]int) error
+
+	// Increment a single numeric property of a mixpanel user.
+	IncrementOne(ctx context.Context, distinctId, prop string, delta int) error
+
+	// Update a mixpanel group with any operation.
+	UpdateGroup(ctx context.Context, groupKey, groupId string, u *Update) error
+
+	// Merge two distinct ids into the same identity
+	Merge(ctx context.Context, distinctId1, distinctId2 string) error
+
+	// Set properties for a mixpanel group.
+	GroupSet(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error
+
+	// Set properties for a mixpanel group, unless they are already set.
+	GroupSetOnce(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error
+
+	// Merge values into list properties of a mixpanel group, without duplicates.
+	GroupUnion(ctx context.Context, groupKey, groupId string, props map[string][]interface{}) error
+
+	// Remove values from list properties of a mixpanel group.
+	GroupRemove(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error
+
+	// Delete properties of a mixpanel group.
+	GroupUnset(ctx context.Context, groupKey, groupId string, properties []string) error
+
+	// Delete a mixpanel group profile.
+	GroupDelete(ctx context.Context, groupKey, groupId string) error
+
+	// Set properties for several mixpanel groups of the same group key.
+	UpdateGroupBatch(ctx context.Context, groupKey string, updates []*GroupUpdate) error
+
+	// Create an alias for an existing distinct id. Aliases can't be batched
+	// with other events.
+	Alias(ctx context.Context, distinctId, newId string) error
+
+	// Create many aliases at once through the import api
+	AliasBatch(ctx context.Context, aliases []AliasPair) error
+
+	// Export raw events using the export api
+	Export(ctx context.Context, params ExportParams) (*ExportReader, error)
+
+	// Run a JQL script
+	JQL(ctx context.Context, script string, params map[string]interface{}) ([]json.RawMessage, error)
+
+	// Count the occurrences of an event over time, split by segment
+	Segmentation(ctx context.Context, params SegmentationParams) (*SegmentationResult, error)
+
+	// Query the conversion of a saved funnel, and list the saved funnels
+	Funnel(ctx context.Context, funnelID int, params FunnelParams) (*FunnelResult, error)
+	ListFunnels(ctx context.Context) ([]FunnelInfo, error)
+
+	// Count the users of cohorts coming back over time
+	Retention(ctx context.Context, params RetentionParams) (*RetentionResult, error)
+
+	// Run a saved Insights report
+	Insights(ctx context.Context, bookmarkID int) (*InsightsResult, error)
+
+	// List the most common events, with their counts of today or by name
+	TopEvents(ctx context.Context, params TopEventsParams) (*TopEventsResult, error)
+	EventNames(ctx context.Context, params TopEventsParams) ([]string, error)
+
+	// Query user profiles
+	QueryEngage(ctx context.Context, params EngageQuery) (*EngageResults, error)
+
+	// Compare user profiles with the properties expected for them
+	Reconcile(ctx context.Context, distinctIDs []string, expected map[string]map[string]interface{}) ([]Mismatch, error)
+
+	// Request the deletion of the data of users, and poll its progress
+	CreateDeletionTask(ctx context.Context, distinctIDs []string, opts DeletionOpts) (taskID string, err error)
+	GetDeletionStatus(ctx context.Context, taskID string) (*DeletionStatus, error)
+
+	// Request a copy of the data of users, and poll its progress
+	CreateRetrievalTask(ctx context.Context, distinctIDs []string) (taskID string, err error)
+	GetRetrievalStatus(ctx context.Context, taskID string) (*RetrievalStatus, error)
+
+	// Replace the content of a lookup table with a CSV file
+	ReplaceLookupTable(ctx context.Context, tableID string, csv io.Reader) error
+
+	// Return the bodies the client would send, without sending them
+	EncodeTrack(distinctID, eventName string, e *Event) (string, error)
+	EncodeImport(distinctID, eventName string, e *Event) (string, error)
+	EncodeUpdate(distinctID string, u *Update) (string, error)
+	EncodeGroupUpdate(groupKey, groupID string, u *Update) (string, error)
+
+	// Check the credentials of the client without sending any data
+	Validate(ctx context.Context) error
+
+	// Send what the client still holds and release its resources
+	Close(ctx context.Context) error
+}
+
+// NopCloser implements the Close method of Mixpanel for clients holding
+// nothing to send. Embed it in your own implementations of Mixpanel.
+type NopCloser struct{}
+
+// Close does nothing and returns nil.
+func (NopCloser) Close(ctx context.Context) error {
+	return nil
+}
+
+// The Mixapanel struct store the mixpanel endpoint and the project token
+type mixpanel struct {
+	Client *http.Client
+	Token  string
+	Secret string
+	ApiURL string
+
+	// Service account used instead of the secret, with the project it
+	// queries
+	ServiceAccountUser   string
+	ServiceAccountSecret string
+	ProjectID            int
+
+	// OAuth token of the GDPR apis
+	ComplianceToken string
+
+	// Endpoints of the raw data export and query apis
+	DataURL  string
+	QueryURL string
+
+	// User-Agent header sent with every request, see WithUserAgent
+	UserAgent string
+
+	// Send event times in milliseconds, see WithMillisecondTime
+	MillisecondTime bool
+
+	// Report rejected events of import requests, see WithVerboseImport
+	VerboseImport bool
+
+	// Retry configuration, see WithRetry
+	MaxAttempts int
+	RetryDelay  time.Duration
+
+	// Compression configuration, see WithCompression
+	Compress             bool
+	CompressionThreshold int
+
+	// Number of chunks of a batch sent at the same time, see
+	// WithConcurrency
+	Concurrency int
+
+	// Skip malformed lines of ImportStream, see WithSkipMalformedLines
+	SkipMalformedLines bool
+
+	// Smallest chunk an import batch is split into when too large, see
+	// WithMinImportChunkSize
+	MinImportChunkSize int
+
+	// Sizes of the chunks of batches, see WithBatchLimits
+	BatchLimits BatchLimits
+
+	// Diagnostics of requests, see WithLogger and WithTracer
+	Logger Logger
+	Tracer Tracer
+
+	// Called after every attempt at sending a request, see WithHook
+	Hooks []RoundTripHook
+
+	// Captures requests instead of sending them, see WithDryRun
+	DryRun *dryRun
+
+	// Timeout of requests whose context has no deadline, see
+	// WithDefaultTimeout
+	DefaultTimeout time.Duration
+
+	// Reject events without token, distinct id or name, see
+	// WithStrictValidation
+	StrictValidation bool
+
+	// Reject invalid property names, see WithPropertyValidation
+	PropertyValidation bool
+
+	// Longest response body quoted in errors and logs, see
+	// WithMaxErrorBodyBytes
+	MaxErrorBodyBytes int
+
+	// Schemas of the events tracked with TrackTyped, see WithEventRegistry
+	Registry *EventRegistry
+
+	// Location the wall clock of timestamps is read in, see
+	// WithProjectTimezone
+	ProjectTimezone *time.Location
+
+	// Derive missing insert ids from the events, see WithAutoInsertID
+	AutoInsertID bool
+
+	// Returns the insert ids of events which have none, see
+	// WithInsertIDFunc
+	InsertIDFunc func(distinctID, eventName string, e *Event) string
+
+	// Flattens nested event properties, see WithFlattenProperties
+	Flatten *FlattenOpts
+
+	// URLs overriding the ones of some endpoints, see WithEndpointURLs
+	EndpointURLs map[string]string
+
+	// Where the form of the track, engage and groups apis is sent, see
+	// WithTransport and WithQueryFallback
+	Transport     Transport
+	QueryFallback bool
+
+	// Sends JSON rather than base64 encoded forms, see WithRawJSONBody
+	RawJSONBody bool
+
+	// How the times of properties are sent, see WithTimeFormat
+	TimeFormat TimeFormat
+
+	// Sends NaN and infinite numbers as null, see WithNonFiniteAsNull
+	NonFiniteAsNull bool
+
+	// Current time, see WithClock
+	Clock func() time.Time
+
+	// Insert ids of the events sent recently, see WithDedup
+	dedup *dedupCache
+
+	// Merged into every event, see SetSuperProperties
+	super superProperties
+}
+
+// batchLimit returns limit, the size of the chunks of a batch set by
+// WithBatchLimits, or max when it is not set.
+func batchLimit(limit, max int) int {
+	if limit < 1 {
+		return max
+	}
+	return limit
+}
+
+// batchSizes returns the sizes of the chunks of TrackBatch and
+// UpdateBatch, see BufferedClient.Flush.
+func (m *mixpanel) batchSizes() (track, engage int) {
+	return batchLimit(m.BatchLimits.Track, MaxTrackBatchSize), batchLimit(m.BatchLimits.Engage, MaxEngageBatchSize)
+}
+
+// token returns override, the token of a single event or update, or the
+// token of the client when it is empty.
+func (m *mixpanel) token(override string) string {
+	if override != "" {
+		return override
+	}
+	return m.Token
+}
+
+// endpointURL returns the URL of one of the endpoints named by
+// WithEndpointURLs, overridden or derived from the base URLs of the client.
+func (m *mixpanel) endpointURL(endpoint string) string {
+	if url, ok := m.EndpointURLs[endpoint]; ok {
+		return url
+	}
+
+	switch endpoint {
+	case "export":
+		return m.DataURL + "/api/2.0/export"
+	case "jql":
+		return m.QueryURL + "/api/2.0/jql"
+	case "segmentation":
+		return m.QueryURL + "/api/2.0/segmentation"
+	case "funnels", "funnels/list", "retention", "insights", "events/top", "events/names":
+		return m.QueryURL + "/api/2.0/" + endpoint
+	case "engage/query":
+		return m.QueryURL + "/api/2.0/engage"
+	case "data-deletions", "data-retrievals":
+		return m.QueryURL + "/api/app/" + endpoint + "/v3.0/"
+	}
+
+	return m.ApiURL + "/" + endpoint
+}
+
+// wallClock returns t, or the time with the same wall clock in the project
+// timezone of the client if it has one.
+func (m *mixpanel) wallClock(t time.Time) time.Time {
+	if m.ProjectTimezone == nil {
+		return t
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), m.ProjectTimezone)
+}
+
+// A mixpanel event
+type Event struct {
+	// IP-address of the user. Leave empty to use autodetect, or set to "0" to
+	// not specify an ip-address.
+	IP string
+
+	// Don't geolocate the event, neither from IP nor from the address the
+	// request was sent from. Takes precedence over IP.
+	DisableGeolocation bool
+
+	// Timestamp. Set to nil to use the current time. Timestamps are sent as
+	// epochs, which are the same whatever the location of the time, unless
+	// the client was created with WithProjectTimezone. Track accepts events
+	// of the last five days, older ones must be sent with Import.
+	Timestamp *time.Time
+
+	// Unique id of the event, sent as $insert_id. Mixpanel ignores events
+	// with the same id, which makes it safe to send an event again. See
+	// NewInsertID to derive one from the event itself.
+	InsertID string
+
+	// Id of the device of an anonymous user, sent as $device_id. Events
+	// tracked with a device id and no distinct id are sent without
+	// distinct_id, and are attributed to the user once an event with both
+	// the device id and a $user_id is tracked, typically on login. This only
+	// applies to projects using simplified identity merge; Alias and Merge
+	// are meant for projects using the original identity model.
+	DeviceID string
+
+	// Token of the project receiving the event, instead of the one of the
+	// client, as when routing the events of tenants to their own projects.
+	// Imports are still authenticated with the credentials of the client,
+	// which must have access to the project.
+	Token string
+
+	// Custom properties. At least one must be specified.
+	Properties map[string]interface{}
+
+	// Ids of the groups the event belongs to, keyed by group key such as
+	// "company_id", as passed to UpdateGroup. Ids are strings or numbers,
+	// or lists of them for events belonging to many groups of a key. They
+	// are sent as properties named by the group keys; events with a
+	// property of the same name and another value are rejected with a
+	// *ValidationError.
+	Groups map[string]interface{}
+}
+
+type TrackEvent struct {
+	DistinctID string
+	EventName  string
+	Event      *Event
+}
+
+// An event to be sent using the import api
+type ImportEvent = TrackEvent
+
+// An update of a user in mixpanel
+type Update struct {
+	// IP-address of the user, used to set $city, $region and $country_code.
+	// Leave empty to use autodetect, or set to "0" to not specify an
+	// ip-address at all.
+	IP string
+
+	// Don't geolocate the user, neither from IP nor from the address the
+	// request was sent from. Takes precedence over IP.
+	DisableGeolocation bool
+
+	// Timestamp. Set to nil to use the current time, or IgnoreTime to not use a
+	// timestamp.
+	Timestamp *time.Time
+
+	// Don't update the $last_seen property of the user, as when backfilling
+	// old updates. Same as setting Timestamp to IgnoreTime, but can be used
+	// along with a timestamp.
+	IgnoreTime bool
+
+	// Update operation such as "$set", "$update" etc.
+	Operation string
+
+	// Apply the update to the given distinct id as is, without resolving it
+	// to an aliased profile first.
+	IgnoreAlias bool
+
+	// Token of the project of the profile, instead of the one of the
+	// client, see Event.Token.
+	Token string
+
+	// Custom properties. At least one must be specified.
+	Properties map[string]interface{}
+}
+
+// An update of one user profile in a batch
+type ProfileUpdate struct {
+	DistinctID string
+	Update     *Update
+}
+
+// An update of one group in a batch
+type GroupUpdate struct {
+	GroupID string
+	Update  *Update
+
+	// Group key of the update, instead of the one of the batch when set
+	GroupKey string
+}
+
+// An alias of one distinct id in a batch
+type AliasPair struct {
+	DistinctID string
+	Alias      string
+}
+
+// Close does nothing, since every call sends its request before returning.
+func (m *mixpanel) Close(ctx context.Context) error {
+	return nil
+}
+
+// Alias create an alias for an existing distinct id. The $create_alias event
+// is authenticated with the project token and must be sent on its own, so it
+// is rejected by TrackBatch; use AliasBatch to create many. Projects using
+// simplified identity merge link anonymous events through Event.DeviceID
+// instead. See https://developer.mixpanel.com/reference/identity-create-alias
+func (m *mixpanel) Alias(ctx context.Context, distinctId, newId string) error {
+	props := map[string]interface{}{
+		"token":       m.Token,
+		"distinct_id": distinctId,
+		"alias":       newId,
+	}
+
+	params := map[string]interface{}{
+		"event":      "$create_alias",
+		"properties": props,
+	}
+
+	return m.send(ctx, "track", params, false)
+}
+
+// Merge merges two distinct ids into a single identity. Merging is done
+// through the import api and requires a client created with a secret. It is
+// not supported by projects using simplified identity merge, see
+// Event.DeviceID. See https://developer.mixpanel.com/reference/identity-merge
+func (m *mixpanel) Merge(ctx context.Context, distinctId1, distinctId2 string) error {
+	if !m.hasCredentials() {
+		return &MixpanelError{URL: m.endpointURL("import"), Err: errors.New("merge requires an api secret or a service account, use NewWithSecret")}
+	}
+
+	props := map[string]interface{}{
+		"token":         m.Token,
+		"$distinct_ids": []string{distinctId1, distinctId2},
+	}
+
+	params := map[string]interface{}{
+		"event":      "$merge",
+		"properties": props,
+	}
+
+	return m.sendImport(ctx, params, false)
+}
+
+// eventToParams returns the payload of the event at index of a batch sent
+// to endpoint. The properties of the event are merged with the super and
+// context properties, checked for NaN and infinite numbers, then flattened
+// with WithFlattenProperties and checked with WithPropertyValidation,
+// failing with a *ValidationError.
+func (m *mixpanel) eventToParams(ctx context.Context, endpoint string, index int, distinctID, eventName string, e *Event) (map[string]interface{}, error) {
+	props := map[string]interface{}{
+		"token":       m.token(e.Token),
+		"distinct_id": distinctID,
+	}
+	if e.DeviceID != "" {
+		props["$device_id"] = e.DeviceID
+		if distinctID == "" {
+			delete(props, "distinct_id")
+		}
+	}
+	if e.DisableGeolocation {
+		props["ip"] = "0"
+	} else if e.IP != "" {
+		props["ip"] = e.IP
+	}
+	if e.Timestamp != nil {
+		if m.MillisecondTime {
+			props["time"] = m.wallClock(*e.Timestamp).UnixMilli()
+		} else {
+			props["time"] = m.wallClock(*e.Timestamp).Unix()
+		}
+	}
+	if e.InsertID != "" {
+		props["$insert_id"] = e.InsertID
+	}
+
+	m.addSuperProperties(props)
+	for key, value := range contextProperties(ctx) {
+		props[key] = value
+	}
+	for key, value := range e.Properties {
+		props[key] = value
+	}
+	for key, value := range e.Groups {
+		props[key] = value
+	}
+	props = m.encodeTimes(props).(map[string]interface{})
+	var verr *ValidationError
+	if !m.NonFiniteAsNull {
+		verr = validateNumbers(index, props)
+	}
+	if verr == nil && m.Flatten != nil {
+		props, verr = m.flattenEvent(index, props)
+	}
+	if verr == nil && m.PropertyValidation {
+		verr = validateProperties(index, props, reservedEventProperties)
+	}
+	if verr != nil {
+		return nil, &MixpanelError{URL: m.endpointURL(endpoint), Err: verr}
+	}
+	if _, ok := props["$insert_id"]; !ok && m.InsertIDFunc != nil {
+		if id := m.InsertIDFunc(distinctID, eventName, e); id != "" {
+			props["$insert_id"] = id
+		}
+	}
+	if _, ok := props["$insert_id"]; !ok && m.AutoInsertID {
+		if id := contentInsertID(eventName, props); id != "" {
+			props["$insert_id"] = id
+		}
+	}
+
+	params := map[string]interface{}{
+		"event":      eventName,
+		"properties": props,
+	}
+
+	return params, nil
+}
+
+// eventsToParams returns the payloads of a batch of events sent to
+// endpoint, or the error of the first invalid one.
+func (m *mixpanel) eventsToParams(ctx context.Context, endpoint string, events []*TrackEvent) ([]map[string]interface{}, error) {
+	params := []map[string]interface{}{}
+
+	for i, event := range events {
+		p, err := m.eventToParams(ctx, endpoint, i, event.DistinctID, event.EventName, event.Event)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, p)
+	}
+
+	return params, nil
+}
+
+// Track create an event for an existing distinct id
+func (m *mixpanel) Track(ctx context.Context, distinctID, eventName string, e *Event) error {
+	if err := m.validateEvent("track", 0, distinctID, eventName, e); err != nil {
+		return err
+	}
+
+	params, err := m.eventToParams(ctx, "track", 0, distinctID, eventName, e)
+	if err != nil {
+		return err
+	}
+
+	autoGeolocate := e.IP == "" && !e.DisableGeolocation
+	return m.send(ctx, "track", params, autoGeolocate)
+}
+
+// AliasBatch creates aliases for existing distinct ids, sending their
+// $create_alias events through the import api, as when migrating users in
+// bulk. It requires a client created with a secret. Batches larger than
+// MaxImportBatchSize are sent as several requests, as described for
+// ImportBatch. Nothing is sent if any of the pairs has an empty id.
+func (m *mixpanel) AliasBatch(ctx context.Context, aliases []AliasPair) error {
+	if !m.hasCredentials() {
+		return &MixpanelError{URL: m.endpointURL("import"), Err: errors.New("batched aliases require an api secret or a service account, use NewWithSecret")}
+	}
+	for i, pair := range aliases {
+		if pair.DistinctID == "" || pair.Alias == "" {
+			return &MixpanelError{URL: m.endpointURL("import"), Err: fmt.Errorf("alias %d has an empty id", i)}
+		}
+	}
+
+	return m.sendChunks(ctx, len(aliases), batchLimit(m.BatchLimits.Import, MaxImportBatchSize), func(start, end int) error {
+		params := []map[string]interface{}{}
+		for _, pair := range aliases[start:end] {
+			params = append(params, map[string]interface{}{
+				"event": "$create_alias",
+				"properties": map[string]interface{}{
+					"token":       m.Token,
+					"distinct_id": pair.DistinctID,
+					"alias":       pair.Alias,
+				},
+			})
+		}
+
+		return m.sendImport(ctx, params, false)
+	})
+}
+
+// TrackBatch creates a batch of events using the track api. Batches larger
+// than MaxTrackBatchSize are sent as several sequential requests, as described
+// for ImportBatch. Aliases must be created with Alias instead.
+func (m *mixpanel) TrackBatch(ctx context.Context, events []*TrackEvent) error {
+	for _, event := range events {
+		if event.EventName == "$create_alias" {
+			return &MixpanelError{URL: m.endpointURL("track"), Err: errors.New("$create_alias events can't be batched, use Alias instead")}
+		}
+	}
+	if err := m.validateEvents("track", events); err != nil {
+		return err
+	}
+	params, err := m.eventsToParams(ctx, "track", events)
+	if err != nil {
+		return err
+	}
+
+	return m.sendChunks(ctx, len(events), batchLimit(m.BatchLimits.Track, MaxTrackBatchSize), func(start, end int) error {
+		return m.send(ctx, "track", params[start:end], false)
+	})
+}
+
+// Import create an event for an existing distinct id
+// See https://developer.mixpanel.com/docs/importing-old-events
+func (m *mixpanel) Import(ctx context.Context, distinctID, eventName string, e *Event) error {
+	if err := m.validateEvent("import", 0, distinctID, eventName, e); err != nil {
+		return err
+	}
+
+	params, err := m.eventToParams(ctx, "import", 0, distinctID, eventName, e)
+	if err != nil {
+		return err
+	}
+
+	autoGeolocate := e.IP == "" && !e.DisableGeolocation
+	return m.sendImport(ctx, params, autoGeolocate)
+}
+
+// ImportBatch takes a batch of events and imports them all. Batches larger
+// than MaxImportBatchSize are sent as several sequential requests, or with as
+// many at the same time as allowed by WithConcurrency. A failing request does
+// not stop the remaining ones unless it was rejected for authentication
+// reasons, and none is sent once ctx is done; all failures are returned as an
+// *ErrBatchFailed.
+// Requests rejected as too large are split in half and sent again.
+func (m *mixpanel) ImportBatch(ctx context.Context, events []*ImportEvent) error {
+	_, err := m.ImportBatchResult(ctx, events)
+	return err
+}
+
+// ImportBatchAt imports a batch of events like ImportBatch, setting the time
+// of the events without Timestamp to at, as when importing a snapshot
+// without times. Events with a Timestamp keep theirs, and events are left
+// untouched.
+func (m *mixpanel) ImportBatchAt(ctx context.Context, events []*ImportEvent, at time.Time) error {
+	return m.ImportBatch(ctx, EventsAt(events, at))
+}
+
+// EventsAt returns copies of events whose Timestamp is at, unless they have
+// one. Events with a Timestamp are returned as is.
+func EventsAt(events []*ImportEvent, at time.Time) []*ImportEvent {
+	timed := make([]*ImportEvent, len(events))
+	for i, event := range events {
+		if event.Event != nil && event.Event.Timestamp != nil {
+			timed[i] = event
+			continue
+		}
+
+		e := Event{}
+		if event.Event != nil {
+			e = *event.Event
+		}
+		e.Timestamp = &at
+		timed[i] = &ImportEvent{DistinctID: event.DistinctID, EventName: event.EventName, Event: &e}
+	}
+	return timed
+}
+
+// ImportBatchResult imports a batch of events like ImportBatch, and returns
+// the outcome reported by mixpanel for all of its requests. The indexes of the
+// rejected events are relative to events.
+func (m *mixpanel) ImportBatchResult(ctx context.Context, events []*ImportEvent) (*ImportResult, error) {
+	total := &ImportResult{}
+
+	if err := m.validateEvents("import", events); err != nil {
+		return total, err
+	}
+	params, err := m.eventsToParams(ctx, "import", events)
+	if err != nil {
+		return total, err
+	}
+
+	var mu sync.Mutex
+	err = m.sendChunks(ctx, len(events), batchLimit(m.BatchLimits.Import, MaxImportBatchSize), func(start, end int) error {
+		return m.importChunk(ctx, events, params, start, end, total, &mu)
+	})
+
+	return total, err
+}
+
+// importChunk imports events[start:end], whose payloads are params[start:end],
+// adding the outcome to total. Chunks rejected as too large are split in half
+// until they fit, or until they are no larger than MinImportChunkSize. mu
+// guards total against chunks sent at the same time.
+func (m *mixpanel) importChunk(ctx context.Context, events []*ImportEvent, params []map[string]interface{}, start, end int, total *ImportResult, mu *sync.Mutex) error {
+	result, err := m.sendImportResult(ctx, params[start:end])
+	if result != nil {
+		for i := range result.Failed {
+			result.Failed[i].Index += start
+		}
+		mu.Lock()
+		total.NumRecordsImported += result.NumRecordsImported
+		total.Failed = append(total.Failed, result.Failed...)
+		mu.Unlock()
+	}
+
+	if !isTooLarge(err) {
+		return err
+	}
+
+	floor := m.MinImportChunkSize
+	if floor < 1 {
+		floor = 1
+	}
+	if end-start <= floor {
+		return &ErrPayloadTooLarge{Index: start, Events: events[start:end], Err: err}
+	}
+
+	middle := start + (end-start)/2
+	var errs []error
+	for _, half := range [][2]int{{start, middle}, {middle, end}} {
+		if err := m.importChunk(ctx, events, params, half[0], half[1], total, mu); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ErrBatchFailed{Errors: errs}
+	}
+
+	return nil
+}
+
+// sendChunks calls send for consecutive chunks of at most size items out of
+// total, as the half-open range [start, end). Up to Concurrency chunks are
+// sent at the same time. A failing chunk does not stop the remaining ones
+// unless it was rejected for authentication reasons, and no chunk is sent
+// once ctx is done; all failures are returned as an *ErrBatchFailed, ordered
+// by chunk.
+func (m *mixpanel) sendChunks(ctx context.Context, total, size int, send func(start, end int) error) error {
+	workers := m.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		mu      sync.Mutex
+		errs    []error
+		offsets []int
+		stopped bool
+	)
+	fail := func(start int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var berr *ErrBatchFailed
+		if errors.As(err, &berr) {
+			for range berr.Errors {
+				offsets = append(offsets, start)
+			}
+			errs = append(errs, berr.Errors...)
+		} else {
+			offsets = append(offsets, start)
+			errs = append(errs, err)
+		}
+		if isAuthError(err) {
+			stopped = true
+		}
+	}
+	isStopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return stopped
+	}
+
+	// The first chunk which was not sent because ctx was done
+	skipped := -1
+	skip := func(start int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if skipped < 0 || start < skipped {
+			skipped = start
+		}
+	}
+
+	starts := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range starts {
+				if isStopped() {
+					continue
+				}
+				if ctx.Err() != nil {
+					skip(start)
+					continue
+				}
+
+				end := start + size
+				if end > total {
+					end = total
+				}
+				if err := send(start, end); err != nil {
+					fail(start, err)
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for start := 0; start < total; start += size {
+		if isStopped() {
+			break
+		}
+		select {
+		case starts <- start:
+		case <-ctx.Done():
+			skip(start)
+			break dispatch
+		}
+	}
+	close(starts)
+	wg.Wait()
+
+	if skipped >= 0 {
+		offsets = append(offsets, skipped)
+		errs = append(errs, ctx.Err())
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	failed := make([]int, len(errs))
+	for i := range failed {
+		failed[i] = i
+	}
+	sort.SliceStable(failed, func(i, j int) bool {
+		return offsets[failed[i]] < offsets[failed[j]]
+	})
+
+	berr := &ErrBatchFailed{}
+	for _, i := range failed {
+		berr.Errors = append(berr.Errors, errs[i])
+		berr.Offsets = append(berr.Offsets, offsets[i])
+	}
+
+	return berr
+}
+
+// Update updates a user in mixpanel. See
+// https://mixpanel.com/help/reference/http#people-analytics-updates
+// Deprecated: Use UpdateUser instead
+func (m *mixpanel) Update(ctx context.Context, distinctId string, u *Update) error {
+	return m.UpdateUser(ctx, distinctId, u)
+}
+
+// UpdateUser: Updates a user in mixpanel. See
+// https://mixpanel.com/help/reference/http#people-analytics-updates
+func (m *mixpanel) UpdateUser(ctx context.Context, distinctId string, u *Update) error {
+	return m.engage(ctx, distinctId, u, u.Properties)
+}
+
+// engage sends a profile update applying the operation of u to value.
+func (m *mixpanel) engage(ctx context.Context, distinctId string, u *Update, value interface{}) error {
+	if err := m.validateProfile(ctx, 0, u.Operation, value); err != nil {
+		return err
+	}
+
+	autoGeolocate := u.IP == "" && !u.DisableGeolocation
+
+	return m.send(ctx, "engage", m.engageParams(ctx, distinctId, u, value), autoGeolocate)
 }
 
-func (err *MixpanelError) Unwrap() error {
-	if err == nil {
-		return nil
+// engageParams returns the payload of a profile update applying the
+// operation of u to value.
+func (m *mixpanel) engageParams(ctx context.Context, distinctId string, u *Update, value interface{}) map[string]interface{} {
+	params := map[string]interface{}{
+		"$token":       m.token(u.Token),
+		"$distinct_id": distinctId,
 	}
 
-	return err.Err
+	if u.DisableGeolocation {
+		params["$ip"] = "0"
+	} else if u.IP != "" {
+		params["$ip"] = u.IP
+	}
+	if u.IgnoreTime || u.Timestamp == IgnoreTime {
+		params["$ignore_time"] = true
+	}
+	if u.Timestamp != nil && u.Timestamp != IgnoreTime {
+		params["$time"] = m.wallClock(*u.Timestamp).Unix()
+	}
+	if u.IgnoreAlias {
+		params["$ignore_alias"] = true
+	}
+
+	if properties, ok := value.(map[string]interface{}); ok && (u.Operation == "$set" || u.Operation == "$set_once") {
+		if ctxProps := contextProperties(ctx); len(ctxProps) > 0 {
+			merged := map[string]interface{}{}
+			for key, value := range ctxProps {
+				merged[key] = value
+			}
+			for key, value := range properties {
+				merged[key] = value
+			}
+			value = merged
+		}
+	}
+
+	params[u.Operation] = m.encodeTimes(value)
+
+	return params
 }
 
-type ErrTrackFailed struct {
-	Message  string
-	Body     []byte
-	HTTPCode int
+// UpdateBatch updates several user profiles, each with its own operation.
+// Batches larger than MaxEngageBatchSize are sent as several sequential
+// requests, as described for ImportBatch. Nothing is sent if any of the
+// updates is nil. See
+// https://developer.mixpanel.com/reference/profile-batch-update
+func (m *mixpanel) UpdateBatch(ctx context.Context, updates []*ProfileUpdate) error {
+	for i, update := range updates {
+		if update == nil || update.Update == nil {
+			return &MixpanelError{URL: m.endpointURL("engage"), Err: fmt.Errorf("profile update %d has no update", i)}
+		}
+		if err := m.validateProfile(ctx, i, update.Update.Operation, update.Update.Properties); err != nil {
+			return err
+		}
+	}
+
+	return m.sendChunks(ctx, len(updates), batchLimit(m.BatchLimits.Engage, MaxEngageBatchSize), func(start, end int) error {
+		params := []map[string]interface{}{}
+		for _, update := range updates[start:end] {
+			params = append(params, m.engageParams(ctx, update.DistinctID, update.Update, update.Update.Properties))
+		}
+
+		return m.send(ctx, "engage", params, false)
+	})
 }
 
-func (err *ErrTrackFailed) Error() string {
-	return fmt.Sprintf("mixpanel did not return 1 when tracking: %s", err.Message)
+// SetOnce sets properties of a user, without overwriting the ones that
+// already have a value. See
+// https://developer.mixpanel.com/reference/profile-set-property-once
+func (m *mixpanel) SetOnce(ctx context.Context, distinctId string, props map[string]interface{}) error {
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$set_once",
+		Properties: props,
+	})
 }
 
-// The Mixapanel struct store the mixpanel endpoint and the project token
-type Mixpanel interface {
-	// Create a mixpanel event using the track api
-	Track(ctx context.Context, distinctId, eventName string, e *Event) error
+// Append appends each value to the list property of a user with the same
+// name. See https://developer.mixpanel.com/reference/profile-append-to-list-property
+func (m *mixpanel) Append(ctx context.Context, distinctId string, props map[string]interface{}) error {
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$append",
+		Properties: props,
+	})
+}
 
-	// Create a mixpanel event using the import api
-	Import(ctx context.Context, distinctId, eventName string, e *Event) error
+// Union merges each list of values into the list property of a user with the
+// same name, ignoring values that are already present. See
+// https://developer.mixpanel.com/reference/user-profile-union
+func (m *mixpanel) Union(ctx context.Context, distinctId string, props map[string][]interface{}) error {
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$union",
+		Properties: unionProperties(props),
+	})
+}
 
-	ImportBatch(ctx context.Context, events []*TrackEvent) error
+func unionProperties(props map[string][]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for key, values := range props {
+		// A nil slice would be encoded as null rather than an empty list.
+		if values == nil {
+			values = []interface{}{}
+		}
+		properties[key] = values
+	}
 
-	// Set properties for a mixpanel user.
-	// Deprecated: Use UpdateUser instead
-	Update(ctx context.Context, distinctId string, u *Update) error
+	return properties
+}
 
-	// Set properties for a mixpanel user.
-	UpdateUser(ctx context.Context, distinctId string, u *Update) error
+// Remove removes each value from the list property of a user with the same
+// name. See https://developer.mixpanel.com/reference/profile-remove-from-list-property
+func (m *mixpanel) Remove(ctx context.Context, distinctId string, props map[string]interface{}) error {
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$remove",
+		Properties: props,
+	})
+}
 
-	// Set properties for a mixpanel group.
-	UpdateGroup(ctx context.Context, groupKey, groupId string, u *Update) error
+// Unset deletes the given properties of a user. At least one property must be
+// given. See https://developer.mixpanel.com/reference/profile-delete-property
+func (m *mixpanel) Unset(ctx context.Context, distinctId string, properties []string) error {
+	if len(properties) == 0 {
+		return &MixpanelError{URL: m.endpointURL("engage"), Err: errors.New("$unset requires at least one property")}
+	}
 
-	// Create an alias for an existing distinct id
-	Alias(ctx context.Context, distinctId, newId string) error
+	return m.engage(ctx, distinctId, &Update{Operation: "$unset"}, properties)
 }
 
-// The Mixapanel struct store the mixpanel endpoint and the project token
-type mixpanel struct {
-	Client *http.Client
-	Token  string
-	Secret string
-	ApiURL string
+// DeleteProfile permanently deletes the profile of a user. See
+// https://developer.mixpanel.com/reference/delete-profile
+func (m *mixpanel) DeleteProfile(ctx context.Context, distinctId string) error {
+	return m.DeleteProfileWithFlag(ctx, distinctId, false)
 }
 
-// A mixpanel event
-type Event struct {
-	// IP-address of the user. Leave empty to use autodetect, or set to "0" to
-	// not specify an ip-address.
-	IP string
+// DeleteProfileWithFlag permanently deletes the profile of a user. If
+// ignoreAlias is set, the profile of distinctId itself is deleted rather than
+// the one it is an alias of.
+func (m *mixpanel) DeleteProfileWithFlag(ctx context.Context, distinctId string, ignoreAlias bool) error {
+	return m.engage(ctx, distinctId, &Update{Operation: "$delete", IgnoreAlias: ignoreAlias}, "")
+}
 
-	// Timestamp. Set to nil to use the current time.
-	Timestamp *time.Time
+// Increment adds the given values to numeric properties of a user. Negative
+// values decrement the property. See
+// https://developer.mixpanel.com/reference/profile-numerical-add
+func (m *mixpanel) Increment(ctx context.Context, distinctId string, props map[string]int) error {
+	properties := map[string]interface{}{}
+	for key, value := range props {
+		properties[key] = value
+	}
 
-	// Custom properties. At least one must be specified.
-	Properties map[string]interface{}
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$add",
+		Properties: properties,
+	})
 }
 
-type TrackEvent struct {
-	DistinctID string
-	EventName  string
-	Event      *Event
+// IncrementOne adds delta to a single numeric property of a user.
+func (m *mixpanel) IncrementOne(ctx context.Context, distinctId, prop string, delta int) error {
+	return m.Increment(ctx, distinctId, map[string]int{prop: delta})
 }
 
-// An update of a user in mixpanel
-type Update struct {
-	// IP-address of the user. Leave empty to use autodetect, or set to "0" to
-	// not specify an ip-address at all.
-	IP string
+// UpdateGroup: Updates a group in mixpanel. A group is named by its group
+// key, the event property holding the ids of the groups such as
+// "company_id", and its id. Every group key has its own profiles, so the
+// same id under two keys names two different groups, and deleting one
+// leaves the other in place. See
+// https://api.mixpanel.com/groups#group-set
+func (m *mixpanel) UpdateGroup(ctx context.Context, groupKey, groupId string, u *Update) error {
+	if err := m.validateGroupUpdate(0, u.Properties); err != nil {
+		return err
+	}
 
-	// Timestamp. Set to nil to use the current time, or IgnoreTime to not use a
-	// timestamp.
-	Timestamp *time.Time
+	return m.send(ctx, "groups", m.groupUpdateParams(groupKey, groupId, u), false)
+}
 
-	// Update operation such as "$set", "$update" etc.
-	Operation string
+// GroupSet sets properties of a group. See
+// https://developer.mixpanel.com/reference/group-set-property
+func (m *mixpanel) GroupSet(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error {
+	if err := m.validateGroupUpdate(0, props); err != nil {
+		return err
+	}
 
-	// Custom properties. At least one must be specified.
-	Properties map[string]interface{}
+	return m.send(ctx, "groups", m.groupParams(groupKey, groupId, "$set", props), false)
 }
 
-// Alias create an alias for an existing distinct id
-func (m *mixpanel) Alias(ctx context.Context, distinctId, newId string) error {
-	props := map[string]interface{}{
-		"token":       m.Token,
-		"distinct_id": distinctId,
-		"alias":       newId,
+// GroupSetOnce sets properties of a group, without overwriting the ones that
+// already have a value. See
+// https://developer.mixpanel.com/reference/group-set-property-once
+func (m *mixpanel) GroupSetOnce(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error {
+	if err := m.validateGroupUpdate(0, props); err != nil {
+		return err
 	}
 
-	params := map[string]interface{}{
-		"event":      "$create_alias",
-		"properties": props,
+	return m.send(ctx, "groups", m.groupParams(groupKey, groupId, "$set_once", props), false)
+}
+
+// GroupUnion merges each list of values into the list property of a group
+// with the same name, ignoring values that are already present. See
+// https://developer.mixpanel.com/reference/group-union
+func (m *mixpanel) GroupUnion(ctx context.Context, groupKey, groupId string, props map[string][]interface{}) error {
+	values := unionProperties(props)
+	if err := m.validateGroupUpdate(0, values); err != nil {
+		return err
 	}
 
-	return m.send(ctx, "track", params, false)
+	return m.send(ctx, "groups", m.groupParams(groupKey, groupId, "$union", values), false)
 }
 
-func (m *mixpanel) eventToParams(distinctID, eventName string, e *Event) map[string]interface{} {
-	props := map[string]interface{}{
-		"token":       m.Token,
-		"distinct_id": distinctID,
-	}
-	if e.IP != "" {
-		props["ip"] = e.IP
-	}
-	if e.Timestamp != nil {
-		props["time"] = e.Timestamp.Unix()
+// GroupRemove removes each value from the list property of a group with the
+// same name. See https://developer.mixpanel.com/reference/group-remove-from-list-property
+func (m *mixpanel) GroupRemove(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error {
+	if err := m.validateGroupUpdate(0, props); err != nil {
+		return err
 	}
 
-	for key, value := range e.Properties {
-		props[key] = value
-	}
+	return m.send(ctx, "groups", m.groupParams(groupKey, groupId, "$remove", props), false)
+}
 
-	params := map[string]interface{}{
-		"event":      eventName,
-		"properties": props,
+// GroupUnset deletes the given properties of a group. At least one property
+// must be given. See https://developer.mixpanel.com/reference/group-delete-property
+func (m *mixpanel) GroupUnset(ctx context.Context, groupKey, groupId string, properties []string) error {
+	if len(properties) == 0 {
+		return &MixpanelError{URL: m.endpointURL("groups"), Err: errors.New("$unset requires at least one property")}
 	}
 
-	return params
+	return m.send(ctx, "groups", m.groupParams(groupKey, groupId, "$unset", properties), false)
 }
 
-// Track create an event for an existing distinct id
-func (m *mixpanel) Track(ctx context.Context, distinctID, eventName string, e *Event) error {
-	autoGeolocate := e.IP == ""
-	return m.send(ctx, "track", m.eventToParams(distinctID, eventName, e), autoGeolocate)
+// GroupDelete permanently deletes the profile of a group. See
+// https://developer.mixpanel.com/reference/delete-group
+func (m *mixpanel) GroupDelete(ctx context.Context, groupKey, groupId string) error {
+	return m.send(ctx, "groups", m.groupParams(groupKey, groupId, "$delete", ""), false)
 }
 
-// Import create an event for an existing distinct id
-// See https://developer.mixpanel.com/docs/importing-old-events
-func (m *mixpanel) Import(ctx context.Context, distinctID, eventName string, e *Event) error {
-	autoGeolocate := e.IP == ""
-	return m.sendImport(ctx, m.eventToParams(distinctID, eventName, e), autoGeolocate)
+// UpdateGroupBatch updates several groups of groupKey, or of the GroupKey of
+// each update when set, so that groups of several keys can be updated
+// together. Batches larger than MaxGroupBatchSize are sent as several
+// sequential requests, as described for ImportBatch. Nothing is sent if any
+// of the updates is nil.
+func (m *mixpanel) UpdateGroupBatch(ctx context.Context, groupKey string, updates []*GroupUpdate) error {
+	for i, update := range updates {
+		if update == nil || update.Update == nil {
+			return &MixpanelError{URL: m.endpointURL("groups"), Err: fmt.Errorf("group update %d has no update", i)}
+		}
+		if err := m.validateGroupUpdate(i, update.Update.Properties); err != nil {
+			return err
+		}
+	}
+
+	return m.sendChunks(ctx, len(updates), batchLimit(m.BatchLimits.Group, MaxGroupBatchSize), func(start, end int) error {
+		params := []map[string]interface{}{}
+		for _, update := range updates[start:end] {
+			key := groupKey
+			if update.GroupKey != "" {
+				key = update.GroupKey
+			}
+			params = append(params, m.groupUpdateParams(key, update.GroupID, update.Update))
+		}
+
+		return m.send(ctx, "groups", params, false)
+	})
 }
 
-// Import batch takes a batch of events and imports them all.
-func (m *mixpanel) ImportBatch(ctx context.Context, events []*TrackEvent) error {
-	if len(events) == 0 {
-		return nil
-	}
+// groupUpdateParams returns the payload of a group update, sent with the
+// token of u if it has one.
+func (m *mixpanel) groupUpdateParams(groupKey, groupId string, u *Update) map[string]interface{} {
+	params := m.groupParams(groupKey, groupId, u.Operation, u.Properties)
+	params["$token"] = m.token(u.Token)
 
-	params := []map[string]interface{}{}
+	return params
+}
 
-	for _, event := range events {
-		params = append(params, m.eventToParams(event.DistinctID, event.EventName, event.Event))
+func (m *mixpanel) groupParams(groupKey, groupId, operation string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"$token":     m.Token,
+		"$group_id":  groupId,
+		"$group_key": groupKey,
+		operation:    m.encodeTimes(value),
 	}
-
-	return m.sendImport(ctx, params, false)
 }
 
-// Update updates a user in mixpanel. See
-// https://mixpanel.com/help/reference/http#people-analytics-updates
-// Deprecated: Use UpdateUser instead
-func (m *mixpanel) Update(ctx context.Context, distinctId string, u *Update) error {
-	return m.UpdateUser(ctx, distinctId, u)
+func (m *mixpanel) to64(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
 }
 
-// UpdateUser: Updates a user in mixpanel. See
-// https://mixpanel.com/help/reference/http#people-analytics-updates
-func (m *mixpanel) UpdateUser(ctx context.Context, distinctId string, u *Update) error {
-	params := map[string]interface{}{
-		"$token":       m.Token,
-		"$distinct_id": distinctId,
+// encodeForm returns the form sent to the track, engage and groups apis,
+// holding the JSON encoding of params in base64, escaped for the query
+// string if query is set. Bodies of clients created with WithRawJSONBody
+// are the JSON list of params instead, see rawJSON.
+func (m *mixpanel) encodeForm(params interface{}, query bool) ([]byte, error) {
+	if m.rawJSON(query) {
+		if _, batch := params.([]map[string]interface{}); !batch {
+			params = []interface{}{params}
+		}
+		return json.Marshal(params)
 	}
 
-	if u.IP != "" {
-		params["$ip"] = u.IP
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if !encodeSmallEvent(buf, params) {
+		if err := json.NewEncoder(buf).Encode(params); err != nil {
+			return nil, err
+		}
 	}
-	if u.Timestamp == IgnoreTime {
-		params["$ignore_time"] = true
-	} else if u.Timestamp != nil {
-		params["$time"] = u.Timestamp.Unix()
+	// Encode terminates the JSON with a newline, which json.Marshal doesn't.
+	data := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+
+	if query {
+		return []byte("data=" + url.QueryEscape(m.to64(data))), nil
 	}
 
-	params[u.Operation] = u.Properties
+	// The form outlives buf, so it is encoded into its own slice.
+	form := make([]byte, len("data=")+base64.StdEncoding.EncodedLen(len(data)))
+	copy(form, "data=")
+	base64.StdEncoding.Encode(form[len("data="):], data)
+	return form, nil
+}
 
-	autoGeolocate := u.IP == ""
+// maxPooledBufferSize is the capacity above which buffers are dropped
+// rather than pooled, so that a few large batches don't keep their memory.
+const maxPooledBufferSize = 64 << 10
 
-	return m.send(ctx, "engage", params, autoGeolocate)
+// bufferPool holds the buffers the JSON of forms is encoded in.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
 }
 
-// UpdateGroup: Updates a group in mixpanel. See
-// https://api.mixpanel.com/groups#group-set
-func (m *mixpanel) UpdateGroup(ctx context.Context, groupKey, groupId string, u *Update) error {
-	params := map[string]interface{}{
-		"$token":     m.Token,
-		"$group_id":  groupId,
-		"$group_key": groupKey,
-	}
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
 
-	params[u.Operation] = u.Properties
+// putBuffer returns buf to the pool, emptied so that its content can't end
+// up in another request.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
 
-	return m.send(ctx, "groups", params, false)
+// rawJSON reports whether data not sent in the query string is sent as
+// JSON rather than as a form, see WithRawJSONBody.
+func (m *mixpanel) rawJSON(query bool) bool {
+	return m.RawJSONBody && !query
 }
 
-func (m *mixpanel) to64(data []byte) string {
-	return base64.StdEncoding.EncodeToString(data)
+// usesQuery reports whether params are sent in the query string, see
+// WithTransport.
+func (m *mixpanel) usesQuery(params interface{}) bool {
+	_, batch := params.([]map[string]interface{})
+	return m.Transport == TransportQuery && !batch
 }
 
 func (m *mixpanel) sendImport(ctx context.Context, params interface{}, autoGeolocate bool) error {
+	_, err := m.sendImportResult(ctx, params)
+	return err
+}
+
+// sendImportResult sends params to the import api and returns the outcome
+// reported by mixpanel. The result is nil when the request failed before
+// mixpanel could report one.
+func (m *mixpanel) sendImportResult(ctx context.Context, params interface{}) (_ *ImportResult, err error) {
 	data, err := json.Marshal(params)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	url := m.ApiURL + "/import?strict=1"
+	query := url.Values{}
+	query.Set("strict", "1")
+	m.setProjectID(query)
+	url := m.endpointURL("import") + "?" + query.Encode()
+
+	ctx, end := m.Tracer.StartSpan(ctx, "import", url, countRecords(params))
+	var status int
+	defer func() { end(status, err) }()
 
+	var resp *http.Response
 	wrapErr := func(err error) error {
-		return &MixpanelError{URL: url, Err: err}
+		return newMixpanelError(url, resp, err)
 	}
 
-	request, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(data)))
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	resp, body, err := m.do(ctx, url, data, header, isIdempotent(params))
+	status = statusCode(resp)
 	if err != nil {
-		return wrapErr(err)
+		return nil, wrapErr(err)
+	}
+
+	type verboseResponse struct {
+		Error              string        `json:"error"`
+		Status             string        `json:"status"`
+		NumRecordsImported int           `json:"num_records_imported"`
+		FailedRecords      []RecordError `json:"failed_records"`
+	}
+
+	var jsonBody verboseResponse
+	err = json.Unmarshal(body, &jsonBody)
+	if derr := m.responseDecodeError(resp, body, err); derr != nil {
+		return nil, wrapErr(derr)
+	}
+	// Error responses don't always follow the documented format, so only
+	// report the decoding error when the request otherwise succeeded.
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return nil, wrapErr(err)
+	}
+
+	result := &ImportResult{
+		NumRecordsImported: jsonBody.NumRecordsImported,
+		Failed:             jsonBody.FailedRecords,
+	}
+
+	if jsonBody.Status != "OK" {
+		errMsg := fmt.Sprintf("error=%s; status=%s; httpCode=%d, body=%s", jsonBody.Error, jsonBody.Status, resp.StatusCode, m.truncateBody(body))
+		err := m.responseError(resp, errMsg, body)
+		if m.VerboseImport {
+			// The error shares the records of result, so that ImportBatchResult
+			// adjusts the indexes of both at once.
+			err = &ErrImportFailed{Result: *result, Err: err}
+		}
+		return result, wrapErr(err)
 	}
-	if m.Secret != "" {
-		request.SetBasicAuth(m.Secret, "")
+
+	return result, nil
+}
+
+func (m *mixpanel) send(ctx context.Context, eventType string, params interface{}, autoGeolocate bool) (err error) {
+	var insertIDs []string
+	if eventType == "track" {
+		if params, insertIDs = m.dedupEvents(params); params == nil {
+			return nil
+		}
 	}
-	request.Header.Set("Content-Type", "application/json")
-	resp, err := m.Client.Do(request)
+
+	query := m.usesQuery(params)
+	data, err := m.encodeForm(params, query)
+
 	if err != nil {
-		return wrapErr(err)
+		return err
 	}
 
-	defer resp.Body.Close()
+	url := m.endpointURL(eventType) + "?verbose=1"
+	endpoint := url
 
-	body, bodyErr := ioutil.ReadAll(resp.Body)
+	ctx, end := m.Tracer.StartSpan(ctx, eventType, url, countRecords(params))
+	var status int
+	defer func() { end(status, err) }()
 
-	if bodyErr != nil {
-		return wrapErr(bodyErr)
+	var resp *http.Response
+	wrapErr := func(err error) error {
+		return newMixpanelError(endpoint, resp, err)
+	}
+
+	header := http.Header{}
+	if length := len(url) + 1 + len(data); query && length > MaxQueryTransportLength {
+		if !m.QueryFallback {
+			return wrapErr(fmt.Errorf("the request is %d bytes long, more than the %d bytes allowed by TransportQuery", length, MaxQueryTransportLength))
+		}
+
+		warnf(m.Logger, "mixpanel: sending %s in the body, the request is %d bytes long, more than the %d bytes allowed by TransportQuery", url, length, MaxQueryTransportLength)
+		query = false
+		if data, err = m.encodeForm(params, false); err != nil {
+			return err
+		}
+	}
+	if query {
+		url += "&" + string(data)
+		data = nil
+	}
+	if m.rawJSON(query) {
+		header.Set("Content-Type", "application/json")
+	} else {
+		header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, body, err := m.do(ctx, url, data, header, isIdempotent(params))
+	status = statusCode(resp)
+	if err != nil {
+		return wrapErr(err)
 	}
 
 	type verboseResponse struct {
 		Error  string `json:"error"`
-		Status string `json:"status"`
+		Status int    `json:"status"`
 	}
 
 	var jsonBody verboseResponse
-	err = json.Unmarshal(body, &jsonBody)
-	if err != nil {
-		return wrapErr(err)
+	if derr := m.responseDecodeError(resp, body, json.Unmarshal(body, &jsonBody)); derr != nil {
+		return wrapErr(derr)
 	}
 
-	// TODO(joey): If some records in the batch failed, return them so they can be retried.
-	if jsonBody.Status != "OK" {
-		errMsg := fmt.Sprintf("error=%s; status=%s; httpCode=%d, body=%s", jsonBody.Error, jsonBody.Status, resp.StatusCode, string(body))
-		return wrapErr(&ErrTrackFailed{Message: errMsg, HTTPCode: resp.StatusCode, Body: body})
+	if jsonBody.Status != 1 {
+		errMsg := fmt.Sprintf("error=%s; status=%d; httpCode=%d", jsonBody.Error, jsonBody.Status, resp.StatusCode)
+		return wrapErr(m.responseError(resp, errMsg, body))
+	}
+
+	if m.dedup != nil {
+		m.dedup.add(insertIDs, m.Clock())
 	}
 
 	return nil
 }
 
-func (m *mixpanel) send(ctx context.Context, eventType string, params interface{}, autoGeolocate bool) error {
-	data, err := json.Marshal(params)
+// do posts data to url, retrying transient failures as configured with
+// WithRetry. The body of the last response is returned along with it.
+func (m *mixpanel) do(ctx context.Context, url string, data []byte, header http.Header, idempotent bool) (*http.Response, []byte, error) {
+	if err := m.checkCredentials(); err != nil {
+		return nil, nil, err
+	}
 
-	if err != nil {
-		return err
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	attempts := m.MaxAttempts
+	if attempts < 1 || !idempotent {
+		attempts = 1
 	}
 
-	url := m.ApiURL + "/" + eventType + "?verbose=1"
+	if m.Compress && len(data) >= m.CompressionThreshold {
+		// Send the body uncompressed if it can't be compressed.
+		if compressed, err := gzipData(data); err == nil {
+			data = compressed
+			header.Set("Content-Encoding", "gzip")
+		}
+	}
 
-	wrapErr := func(err error) error {
-		return &MixpanelError{URL: url, Err: err}
+	for attempt := 1; ; attempt++ {
+		m.Logger.Debugf("mixpanel: POST %s (attempt %d)", url, attempt)
+		resp, body, err := m.doOnce(ctx, url, data, header, attempt)
+		if err != nil {
+			m.Logger.Errorf("mixpanel: POST %s failed: %v", url, err)
+		} else if resp.StatusCode >= 400 {
+			m.Logger.Errorf("mixpanel: POST %s returned %d: %s", url, resp.StatusCode, m.truncateBody(body))
+		} else {
+			m.Logger.Debugf("mixpanel: POST %s returned %d", url, resp.StatusCode)
+		}
+
+		rateLimited := resp != nil && resp.StatusCode == http.StatusTooManyRequests
+
+		limit := attempts
+		// Rate limited requests are retried once even without WithRetry.
+		if rateLimited && idempotent && m.MaxAttempts < 1 {
+			limit = 2
+		}
+		if attempt >= limit || !shouldRetry(resp, err) || ctx.Err() != nil {
+			return resp, body, err
+		}
+
+		delay := m.backoff(attempt)
+		if rateLimited {
+			if delay == 0 {
+				delay = DefaultRateLimitDelay
+			}
+			if d, ok := retryAfter(resp, m.Clock()); ok {
+				delay = d
+			}
+			// Give up right away rather than wait past the deadline.
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return resp, body, err
+			}
+		}
+
+		m.Logger.Debugf("mixpanel: retrying POST %s in %s", url, delay)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// gzipWriterPool holds the writers gzipData compresses with, which are
+// costly to allocate.
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+func gzipData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzipWriterPool.Get().(*gzip.Writer)
+	w.Reset(&buf)
+	// Writers are only pooled once closed, so that they don't keep
+	// unflushed data around.
+	if _, err := w.Write(data); err != nil {
+		return nil, err
 	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	w.Reset(nil)
+	gzipWriterPool.Put(w)
+
+	return buf.Bytes(), nil
+}
 
-	request, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader("data="+m.to64(data)))
+func (m *mixpanel) doOnce(ctx context.Context, url string, data []byte, header http.Header, attempt int) (*http.Response, []byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
 	if err != nil {
-		return wrapErr(err)
+		return nil, nil, err
 	}
-	if m.Secret != "" {
-		request.SetBasicAuth(m.Secret, "")
+	for key, values := range header {
+		request.Header[key] = values
 	}
-	resp, err := m.Client.Do(request)
+	m.authorize(request)
+	if m.UserAgent != "" {
+		request.Header.Set("User-Agent", m.UserAgent)
+	}
+	start := time.Now()
+	resp, err := m.roundTrip(request)
 	if err != nil {
-		return wrapErr(err)
+		m.runHooks(request, nil, nil, err, attempt, time.Since(start))
+		return nil, nil, err
 	}
 
 	defer resp.Body.Close()
 
-	body, bodyErr := ioutil.ReadAll(resp.Body)
+	body, err := ioutil.ReadAll(resp.Body)
+	m.runHooks(request, resp, body, err, attempt, time.Since(start))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, body, nil
+}
+
+// Version is the version of this library.
+const Version = "1.0.0"
+
+// DefaultUserAgent is the User-Agent header sent with requests of clients
+// created without WithUserAgent.
+const DefaultUserAgent = "perfalytics-mixpanel-go/" + Version
+
+// Region is the data residency region of a mixpanel project.
+type Region string
+
+const (
+	RegionUS Region = "us"
+	RegionEU Region = "eu"
+	RegionIN Region = "in"
+)
 
-	if bodyErr != nil {
-		return wrapErr(bodyErr)
+// apiURL returns the ingestion endpoint serving the region. Unknown regions
+// fall back to the US endpoint.
+func (r Region) apiURL() string {
+	switch r {
+	case RegionEU:
+		return "https://api-eu.mixpanel.com"
+	case RegionIN:
+		return "https://api-in.mixpanel.com"
+	default:
+		return "https://api.mixpanel.com"
 	}
+}
 
-	type verboseResponse struct {
-		Error  string `json:"error"`
-		Status int    `json:"status"`
+// NewClient returns the client instance configured with opts. Without any
+// option, the client sends requests to "https://api.mixpanel.com" using a
+// client shared with the other clients, see NewHTTPTransport.
+func NewClient(token string, opts ...Option) Mixpanel {
+	m := &mixpanel{
+		Client: defaultHTTPClient,
+		Token:  token,
+		ApiURL: RegionUS.apiURL(),
+
+		DataURL:  RegionUS.dataURL(),
+		QueryURL: RegionUS.queryURL(),
+
+		UserAgent: DefaultUserAgent,
+		Logger:    nopLogger{},
+		Tracer:    nopTracer{},
+
+		MaxErrorBodyBytes: DefaultMaxErrorBodyBytes,
+		Clock:             time.Now,
 	}
 
-	var jsonBody verboseResponse
-	json.Unmarshal(body, &jsonBody)
+	for _, opt := range opts {
+		opt(m)
+	}
 
-	if jsonBody.Status != 1 {
-		errMsg := fmt.Sprintf("error=%s; status=%d; httpCode=%d", jsonBody.Error, jsonBody.Status, resp.StatusCode)
-		return wrapErr(&ErrTrackFailed{Message: errMsg, HTTPCode: resp.StatusCode, Body: body})
+	return m
+}
+
+// dataURL returns the raw data export endpoint serving the region.
+func (r Region) dataURL() string {
+	switch r {
+	case RegionEU:
+		return "https://data-eu.mixpanel.com"
+	case RegionIN:
+		return "https://data-in.mixpanel.com"
+	default:
+		return "https://data.mixpanel.com"
 	}
+}
 
-	return nil
+// queryURL returns the query api endpoint serving the region.
+func (r Region) queryURL() string {
+	switch r {
+	case RegionEU:
+		return "https://eu.mixpanel.com"
+	case RegionIN:
+		return "https://in.mixpanel.com"
+	default:
+		return "https://mixpanel.com"
+	}
 }
 
 // New returns the client instance. If apiURL is blank, the default will be used
 // ("https://api.mixpanel.com").
 func New(token, apiURL string) Mixpanel {
-	return NewFromClient(http.DefaultClient, token, apiURL)
+	return NewFromClient(defaultHTTPClient, token, apiURL)
 }
 
 // NewWithSecret returns the client instance using a secret.If apiURL is blank,
 // the default will be used ("https://api.mixpanel.com").
 func NewWithSecret(token, secret, apiURL string) Mixpanel {
-	return NewFromClientWithSecret(http.DefaultClient, token, secret, apiURL)
+	return NewFromClientWithSecret(defaultHTTPClient, token, secret, apiURL)
+}
+
+// NewWithRegion returns the client instance for a project stored in the given
+// data residency region.
+func NewWithRegion(token string, region Region) Mixpanel {
+	return NewClient(token, WithRegion(region))
 }
 
 // NewFromClient creates a client instance using the specified client instance. This is useful
@@ -352,14 +1978,5 @@ func NewFromClient(c *http.Client, token, apiURL string) Mixpanel {
 
 // NewFromClientWithSecret creates a client instance using the specified client instance and secret.
 func NewFromClientWithSecret(c *http.Client, token, secret, apiURL string) Mixpanel {
-	if apiURL == "" {
-		apiURL = "https://api.mixpanel.com"
-	}
-
-	return &mixpanel{
-		Client: c,
-		Token:  token,
-		Secret: secret,
-		ApiURL: apiURL,
-	}
+	return NewClient(token, WithHTTPClient(c), WithSecret(secret), WithBaseURL(apiURL))
 }
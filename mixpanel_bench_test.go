@@ -0,0 +1,119 @@
+package mixpanel
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func benchmarkEvent() *Event {
+	timestamp := time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)
+	return &Event{
+		Timestamp: &timestamp,
+		InsertID:  "5d958f87-542d-4c10-9422-0ed75893dc81",
+		Properties: map[string]interface{}{
+			"Referred By": "Friend",
+			"Plan":        "Premium",
+			"Seats":       3,
+			"Tags":        []string{"a", "b"},
+		},
+	}
+}
+
+func BenchmarkEncodeForm(b *testing.B) {
+	m := NewClient("e3bc4100330c35722740fb8c6f5abddc").(*mixpanel)
+	params, err := m.eventToParams(context.Background(), "track", 0, "13793", "Signed Up", benchmarkEvent())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.encodeForm(params, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeFormBatch(b *testing.B) {
+	m := NewClient("e3bc4100330c35722740fb8c6f5abddc").(*mixpanel)
+	var events []*TrackEvent
+	for i := 0; i < 50; i++ {
+		events = append(events, &TrackEvent{DistinctID: "13793", EventName: "Signed Up", Event: benchmarkEvent()})
+	}
+	params, err := m.eventsToParams(context.Background(), "track", events)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.encodeForm(params, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGzipData(b *testing.B) {
+	m := NewClient("e3bc4100330c35722740fb8c6f5abddc").(*mixpanel)
+	var events []*TrackEvent
+	for i := 0; i < 50; i++ {
+		events = append(events, &TrackEvent{DistinctID: "13793", EventName: "Signed Up", Event: benchmarkEvent()})
+	}
+	params, err := m.eventsToParams(context.Background(), "track", events)
+	if err != nil {
+		b.Fatal(err)
+	}
+	data, err := m.encodeForm(params, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gzipData(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEncodeFormSmall compares the encoding of a small event with
+// encodeSmallEvent and with encoding/json.
+func BenchmarkEncodeFormSmall(b *testing.B) {
+	m := NewClient("e3bc4100330c35722740fb8c6f5abddc").(*mixpanel)
+	params, err := m.eventToParams(context.Background(), "track", 0, "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{"Plan": "Premium"},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("small", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := m.encodeForm(params, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("general", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf := getBuffer()
+			if err := json.NewEncoder(buf).Encode(params); err != nil {
+				b.Fatal(err)
+			}
+			data := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+			form := make([]byte, len("data=")+base64.StdEncoding.EncodedLen(len(data)))
+			copy(form, "data=")
+			base64.StdEncoding.Encode(form[len("data="):], data)
+			putBuffer(buf)
+		}
+	})
+}
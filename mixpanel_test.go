@@ -1,8 +1,11 @@
 package mixpanel
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +13,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -86,6 +90,167 @@ func TestTrack(t *testing.T) {
 	}
 }
 
+func TestTrackGroups(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{
+			"Referred By": "Friend",
+		},
+		Groups: map[string]interface{}{
+			"company_id": "11",
+			"team_id":    []interface{}{"a", "b"},
+		},
+	})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"Referred By\":\"Friend\",\"company_id\":\"11\",\"distinct_id\":\"13793\",\"team_id\":[\"a\",\"b\"],\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	var verr *ValidationError
+	for _, e := range []*Event{
+		{Groups: map[string]interface{}{"": "11"}},
+		{Groups: map[string]interface{}{"company_id": map[string]interface{}{"id": "11"}}},
+		{Groups: map[string]interface{}{"company_id": "11"}, Properties: map[string]interface{}{"company_id": "12"}},
+	} {
+		if err := client.Track(context.TODO(), "13793", "Signed Up", e); !errors.As(err, &verr) || verr.Field != "groups" {
+			t.Errorf("Track with groups %+v should fail validation: %v", e.Groups, err)
+		}
+	}
+
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Groups:     map[string]interface{}{"company_id": "11"},
+		Properties: map[string]interface{}{"company_id": "11"},
+	}); errors.As(err, &verr) {
+		t.Errorf("Track with a property matching its group should not fail validation: %v", err)
+	}
+}
+
+func TestTrackTimestamp(t *testing.T) {
+	setup()
+	defer teardown()
+
+	queued := time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{Timestamp: &queued})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"time\":1457018273,\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+	if path := LastRequest.URL.Path; path != "/track" {
+		t.Errorf("path returned %+v, want %+v", path, "/track")
+	}
+}
+
+func TestTokenOverride(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL))
+	update := &Update{Operation: "$set", Token: "tenant-token", Properties: map[string]interface{}{"Plan": "Premium"}}
+
+	tests := []struct {
+		name string
+		send func()
+		want string
+	}{
+		{
+			"track",
+			func() { client.Track(context.TODO(), "13793", "Signed Up", &Event{Token: "tenant-token"}) },
+			"{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"token\":\"tenant-token\"}}",
+		},
+		{
+			"import",
+			func() { client.Import(context.TODO(), "13793", "Signed Up", &Event{Token: "tenant-token"}) },
+			"{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"token\":\"tenant-token\"}}",
+		},
+		{
+			"engage",
+			func() { client.UpdateUser(context.TODO(), "13793", update) },
+			"{\"$distinct_id\":\"13793\",\"$set\":{\"Plan\":\"Premium\"},\"$token\":\"tenant-token\"}",
+		},
+		{
+			"engage batch",
+			func() { client.UpdateBatch(context.TODO(), []*ProfileUpdate{{DistinctID: "13793", Update: update}}) },
+			"[{\"$distinct_id\":\"13793\",\"$set\":{\"Plan\":\"Premium\"},\"$token\":\"tenant-token\"}]",
+		},
+		{
+			"groups",
+			func() { client.UpdateGroup(context.TODO(), "company", "Acme", update) },
+			"{\"$group_id\":\"Acme\",\"$group_key\":\"company\",\"$set\":{\"Plan\":\"Premium\"},\"$token\":\"tenant-token\"}",
+		},
+	}
+
+	for _, test := range tests {
+		test.send()
+		if !reflect.DeepEqual(decodeBody(), test.want) {
+			t.Errorf("%s: Post body returned %+v, want %+v", test.name, decodeBody(), test.want)
+		}
+	}
+}
+
+func TestTrackDeviceID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Track(context.TODO(), "", "Viewed Pricing", &Event{DeviceID: "device-1"})
+
+	want := "{\"event\":\"Viewed Pricing\",\"properties\":{\"$device_id\":\"device-1\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.Track(context.TODO(), "13793", "Logged In", &Event{
+		DeviceID:   "device-1",
+		Properties: map[string]interface{}{"$user_id": "13793"},
+	})
+
+	want = "{\"event\":\"Logged In\",\"properties\":{\"$device_id\":\"device-1\",\"$user_id\":\"13793\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestTrackGeolocation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		IP:         "203.0.113.7",
+		Properties: map[string]interface{}{},
+	})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"ip\":\"203.0.113.7\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		IP:                 "203.0.113.7",
+		DisableGeolocation: true,
+		Properties:         map[string]interface{}{},
+	})
+
+	want = "{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"ip\":\"0\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
 func TestImport(t *testing.T) {
 	setup()
 	defer teardown()
@@ -171,6 +336,110 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateGeolocation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.UpdateUser(context.TODO(), "13793", &Update{
+		IP:        "203.0.113.7",
+		Operation: "$set",
+		Properties: map[string]interface{}{
+			"Address": "1313 Mockingbird Lane",
+		},
+	})
+
+	want := "{\"$distinct_id\":\"13793\",\"$ip\":\"203.0.113.7\",\"$set\":{\"Address\":\"1313 Mockingbird Lane\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.UpdateUser(context.TODO(), "13793", &Update{
+		IP:                 "203.0.113.7",
+		DisableGeolocation: true,
+		Operation:          "$set",
+		Properties: map[string]interface{}{
+			"Address": "1313 Mockingbird Lane",
+		},
+	})
+
+	want = "{\"$distinct_id\":\"13793\",\"$ip\":\"0\",\"$set\":{\"Address\":\"1313 Mockingbird Lane\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestUpdateTimestamp(t *testing.T) {
+	setup()
+	defer teardown()
+
+	lastSeen := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	client.UpdateUser(context.TODO(), "13793", &Update{
+		Timestamp: &lastSeen,
+		Operation: "$set",
+		Properties: map[string]interface{}{
+			"Plan": "Premium",
+		},
+	})
+
+	want := "{\"$distinct_id\":\"13793\",\"$set\":{\"Plan\":\"Premium\"},\"$time\":1456833600,\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.UpdateUser(context.TODO(), "13793", &Update{
+		Timestamp: IgnoreTime,
+		Operation: "$set",
+		Properties: map[string]interface{}{
+			"Plan": "Premium",
+		},
+	})
+
+	want = "{\"$distinct_id\":\"13793\",\"$ignore_time\":true,\"$set\":{\"Plan\":\"Premium\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.UpdateUser(context.TODO(), "13793", &Update{
+		Timestamp:  &lastSeen,
+		IgnoreTime: true,
+		Operation:  "$set",
+		Properties: map[string]interface{}{
+			"Plan": "Premium",
+		},
+	})
+
+	want = "{\"$distinct_id\":\"13793\",\"$ignore_time\":true,\"$set\":{\"Plan\":\"Premium\"},\"$time\":1456833600,\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.UpdateUser(context.TODO(), "13793", &Update{
+		IgnoreAlias: true,
+		Operation:   "$set",
+		Properties: map[string]interface{}{
+			"Plan": "Premium",
+		},
+	})
+
+	want = "{\"$distinct_id\":\"13793\",\"$ignore_alias\":true,\"$set\":{\"Plan\":\"Premium\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
 func TestError(t *testing.T) {
 	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
@@ -206,6 +475,190 @@ func TestError(t *testing.T) {
 	assertErrTrackFailed(client.Import(context.TODO(), "1", "name", &Event{}))
 }
 
+func TestVerboseResponse(t *testing.T) {
+	body := `{"error": null, "status": 1}`
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(body))
+	}))
+	defer teardown()
+
+	client = New("e3bc4100330c35722740fb8c6f5abddc", ts.URL)
+
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{}); err != nil {
+		t.Errorf("Track returned an error: %v", err)
+	}
+	if verbose := LastRequest.URL.Query().Get("verbose"); verbose != "1" {
+		t.Errorf("verbose returned %+v, want 1", verbose)
+	}
+
+	body = `{"error": "Invalid token", "status": 0}`
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	var terr *ErrTrackFailed
+	if !errors.As(err, &terr) {
+		t.Fatalf("Error should be a *ErrTrackFailed: %v", err)
+	}
+	if terr.Message != "error=Invalid token; status=0; httpCode=200" {
+		t.Errorf("Wrong message carried in the *ErrTrackFailed: %q", terr.Message)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "1234")
+		w.WriteHeader(500)
+		w.Write([]byte(`{"error": "internal error", "status": 0}`))
+	}))
+	defer teardown()
+
+	client = New("e3bc4100330c35722740fb8c6f5abddc", ts.URL)
+
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	var merr *MixpanelError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Error should be a *MixpanelError: %v", err)
+	}
+	if merr.StatusCode != 500 {
+		t.Errorf("StatusCode returned %+v, want 500", merr.StatusCode)
+	}
+	if id := merr.Header.Get("X-Request-Id"); id != "1234" {
+		t.Errorf("X-Request-Id header returned %+v, want 1234", id)
+	}
+
+	var terr *ErrTrackFailed
+	if !errors.As(err, &terr) {
+		t.Errorf("Error should still unwrap to an *ErrTrackFailed: %v", err)
+	}
+}
+
+func TestErrorTypes(t *testing.T) {
+	status := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"error": "some error", "status": 0}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithQueryURL(ts.URL), WithSecret("mysecret"), WithRetry(1, 0))
+
+	var aerr *AuthError
+	var rerr *RateLimitError
+	var serr *ServerError
+	var berr *BadRequestError
+	tests := []struct {
+		status int
+		target interface{}
+	}{
+		{401, &aerr},
+		{403, &aerr},
+		{429, &rerr},
+		{400, &berr},
+		{413, &berr},
+		{500, &serr},
+		{503, &serr},
+	}
+
+	for _, test := range tests {
+		status = test.status
+
+		err := client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+		var terr *ErrTrackFailed
+		if !errors.As(err, test.target) || !errors.As(err, &terr) {
+			t.Errorf("Track error for status %d should be a %T wrapping an *ErrTrackFailed: %v", test.status, test.target, err)
+		}
+
+		_, err = client.JQL(context.TODO(), "function main() {}", nil)
+		var qerr *ErrQueryFailed
+		if test.status != 429 && (!errors.As(err, test.target) || !errors.As(err, &qerr)) {
+			t.Errorf("JQL error for status %d should be a %T wrapping an *ErrQueryFailed: %v", test.status, test.target, err)
+		}
+	}
+}
+
+func TestResponseDecodeError(t *testing.T) {
+	status, body := 200, ""
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithQueryURL(ts.URL), WithSecret("mysecret"))
+	page := "<html><body>Request blocked</body></html>"
+
+	for _, test := range []struct {
+		status int
+		body   string
+	}{
+		{200, page},
+		{403, page},
+		{200, `{"error": null, "status": 1`},
+	} {
+		status, body = test.status, test.body
+
+		for name, send := range map[string]func() error{
+			"Track":  func() error { return client.Track(context.TODO(), "13793", "Signed Up", &Event{}) },
+			"Import": func() error { return client.Import(context.TODO(), "13793", "Signed Up", &Event{}) },
+			"JQL": func() error {
+				_, err := client.JQL(context.TODO(), "function main() {}", nil)
+				return err
+			},
+		} {
+			err := send()
+
+			var derr *ResponseDecodeError
+			if !errors.As(err, &derr) {
+				t.Errorf("%s error for a %d %q response should be a *ResponseDecodeError: %v", name, test.status, test.body, err)
+				continue
+			}
+			if derr.ContentType != "text/html; charset=utf-8" || derr.Body != test.body {
+				t.Errorf("%s returned %+v, want the content type and body of the response", name, derr)
+			}
+
+			var aerr *AuthError
+			if got := errors.As(err, &aerr); got != (test.status == 403) {
+				t.Errorf("%s error for a %d response wraps an *AuthError: %v", name, test.status, got)
+			}
+		}
+	}
+}
+
+func TestErrorBodyTruncation(t *testing.T) {
+	body := `{"error": "` + strings.Repeat("x", 3000) + `", "status": "Bad Request"}`
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(body))
+	}))
+	defer teardown()
+
+	for _, test := range []struct {
+		opts []Option
+		want string
+	}{
+		{nil, body[:DefaultMaxErrorBodyBytes] + "...(truncated)"},
+		{[]Option{WithMaxErrorBodyBytes(10)}, body[:10] + "...(truncated)"},
+		{[]Option{WithMaxErrorBodyBytes(0)}, body},
+	} {
+		client = NewClient("e3bc4100330c35722740fb8c6f5abddc", append(test.opts, WithSecret("mysecret"), WithBaseURL(ts.URL))...)
+		err := client.Import(context.TODO(), "13793", "Signed Up", &Event{})
+
+		var terr *ErrTrackFailed
+		if !errors.As(err, &terr) {
+			t.Fatalf("Error should be an *ErrTrackFailed: %v", err)
+		}
+		if !strings.HasSuffix(terr.Message, "body="+test.want) {
+			t.Errorf("Message returned %d bytes, want the body quoted as %d bytes", len(terr.Message), len(test.want))
+		}
+		if string(terr.Body) != body {
+			t.Errorf("Body should never be truncated")
+		}
+	}
+}
+
 func TestUnwrapCompatible(t *testing.T) {
 	mErr := &MixpanelError{Err: context.DeadlineExceeded}
 	err := error(mErr)
@@ -214,3 +667,1052 @@ func TestUnwrapCompatible(t *testing.T) {
 		t.Error("not compatible with unwrap")
 	}
 }
+
+func TestTrackBatch(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		w.WriteHeader(200)
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = New("e3bc4100330c35722740fb8c6f5abddc", ts.URL)
+
+	if err := client.TrackBatch(context.TODO(), nil); err != nil {
+		t.Errorf("empty batch returned an error: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("empty batch made %d requests, want 0", requests)
+	}
+
+	events := []*TrackEvent{}
+	for i := 0; i < 120; i++ {
+		events = append(events, &TrackEvent{
+			DistinctID: "13793",
+			EventName:  "Signed Up",
+			Event: &Event{
+				Properties: map[string]interface{}{
+					"Referred By": "Friend",
+				},
+			},
+		})
+	}
+
+	if err := client.TrackBatch(context.TODO(), events); err != nil {
+		t.Errorf("TrackBatch returned an error: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("TrackBatch made %d requests, want 3", requests)
+	}
+
+	want := "[" + strings.Repeat("{\"event\":\"Signed Up\",\"properties\":{\"Referred By\":\"Friend\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}},", 19) +
+		"{\"event\":\"Signed Up\",\"properties\":{\"Referred By\":\"Friend\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}]"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/track"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+}
+
+func TestImportBatch(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		w.WriteHeader(200)
+		w.Write([]byte(`{"code": 200, "num_records_imported": 1, "status": "OK"}`))
+	}))
+	defer teardown()
+
+	client = NewWithSecret("e3bc4100330c35722740fb8c6f5abddc", "mysecret", ts.URL)
+
+	events := []*ImportEvent{}
+	for i := 0; i < 4500; i++ {
+		events = append(events, &ImportEvent{
+			DistinctID: "13793",
+			EventName:  "Signed Up",
+			Event:      &Event{},
+		})
+	}
+
+	if err := client.ImportBatch(context.TODO(), events); err != nil {
+		t.Errorf("ImportBatch returned an error: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("ImportBatch made %d requests, want 3", requests)
+	}
+
+	want := "/import"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+}
+
+func TestImportBatchAt(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		w.WriteHeader(200)
+		w.Write([]byte(`{"code": 200, "num_records_imported": 3, "status": "OK"}`))
+	}))
+	defer teardown()
+
+	client = NewWithSecret("e3bc4100330c35722740fb8c6f5abddc", "mysecret", ts.URL)
+
+	at := time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)
+	own := time.Date(2016, 3, 1, 8, 0, 0, 0, time.UTC)
+	untimed := &Event{Properties: map[string]interface{}{"Plan": "Premium"}}
+	events := []*ImportEvent{
+		{DistinctID: "13793", EventName: "Signed Up", Event: untimed},
+		{DistinctID: "13794", EventName: "Signed Up", Event: &Event{Timestamp: &own}},
+		{DistinctID: "13795", EventName: "Signed Up"},
+	}
+
+	if err := client.ImportBatchAt(context.TODO(), events, at); err != nil {
+		t.Fatalf("ImportBatchAt returned an error: %v", err)
+	}
+
+	var body []struct {
+		Properties map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal(LastPost, &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 3 {
+		t.Fatalf("ImportBatchAt sent %d events, want 3", len(body))
+	}
+	for i, want := range []int64{at.Unix(), own.Unix(), at.Unix()} {
+		if got := body[i].Properties["time"]; got != float64(want) {
+			t.Errorf("time of event %d returned %v, want %v", i, got, want)
+		}
+	}
+	if body[0].Properties["Plan"] != "Premium" {
+		t.Errorf("properties of event 0 returned %+v", body[0].Properties)
+	}
+
+	if untimed.Timestamp != nil {
+		t.Errorf("ImportBatchAt should not modify the events")
+	}
+}
+
+func TestImportBatchSplitsLargeBatches(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var events []map[string]map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&events)
+
+		for _, e := range events {
+			if len(events) > 2 || e["properties"]["huge"] == true {
+				w.WriteHeader(413)
+				w.Write([]byte(`{"code": 413, "error": "request body too large", "status": "Request Entity Too Large"}`))
+				return
+			}
+		}
+
+		w.WriteHeader(200)
+		fmt.Fprintf(w, `{"code": 200, "num_records_imported": %d, "status": "OK"}`, len(events))
+	}))
+	defer teardown()
+
+	client = NewWithSecret("e3bc4100330c35722740fb8c6f5abddc", "mysecret", ts.URL)
+
+	events := []*ImportEvent{}
+	for i := 0; i < 8; i++ {
+		events = append(events, &ImportEvent{
+			DistinctID: "13793",
+			EventName:  "Signed Up",
+			Event:      &Event{Properties: map[string]interface{}{"huge": i == 5}},
+		})
+	}
+
+	result, err := client.ImportBatchResult(context.TODO(), events)
+
+	var berr *ErrBatchFailed
+	if !errors.As(err, &berr) || len(berr.Errors) != 1 {
+		t.Fatalf("Error should be an *ErrBatchFailed with a single error: %v", err)
+	}
+	var perr *ErrPayloadTooLarge
+	if !errors.As(berr.Errors[0], &perr) {
+		t.Fatalf("Error should be an *ErrPayloadTooLarge: %v", berr.Errors[0])
+	}
+	if perr.Index != 5 || len(perr.Events) != 1 || perr.Events[0] != events[5] {
+		t.Errorf("Wrong events reported as too large: %+v", perr)
+	}
+	if result.NumRecordsImported != 7 {
+		t.Errorf("NumRecordsImported returned %d, want 7", result.NumRecordsImported)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL), WithMinImportChunkSize(4))
+
+	_, err = client.ImportBatchResult(context.TODO(), events)
+	if !errors.As(err, &berr) || len(berr.Errors) != 2 {
+		t.Fatalf("Error should be an *ErrBatchFailed with two errors: %v", err)
+	}
+	for i, err := range berr.Errors {
+		if !errors.As(err, &perr) || perr.Index != 4*i || len(perr.Events) != 4 {
+			t.Errorf("Chunks of 4 events should not be split: %v", err)
+		}
+	}
+}
+
+func TestImportBatchStopsOnAuthError(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(401)
+		w.Write([]byte(`{"error": "Invalid API secret", "status": 0}`))
+	}))
+	defer teardown()
+
+	client = NewWithSecret("e3bc4100330c35722740fb8c6f5abddc", "badsecret", ts.URL)
+
+	events := []*ImportEvent{}
+	for i := 0; i < 4500; i++ {
+		events = append(events, &ImportEvent{DistinctID: "1", EventName: "name", Event: &Event{}})
+	}
+
+	err := client.ImportBatch(context.TODO(), events)
+
+	var berr *ErrBatchFailed
+	if !errors.As(err, &berr) {
+		t.Fatalf("Error should be a *ErrBatchFailed: %v", err)
+	}
+	if len(berr.Errors) != 1 {
+		t.Errorf("ErrBatchFailed carries %d errors, want 1", len(berr.Errors))
+	}
+	if requests != 1 {
+		t.Errorf("ImportBatch made %d requests, want 1", requests)
+	}
+}
+
+func TestTrackBatchConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight, requests := 0, 0, 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		r.ParseForm()
+		data, _ := base64.StdEncoding.DecodeString(r.PostForm.Get("data"))
+		var events []map[string]map[string]interface{}
+		json.Unmarshal(data, &events)
+
+		time.Sleep(20 * time.Millisecond)
+
+		if chunk := events[0]["properties"]["chunk"]; chunk == 3.0 || chunk == 7.0 {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error": "invalid event", "status": 0}`))
+			return
+		}
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithConcurrency(4))
+
+	events := []*TrackEvent{}
+	for i := 0; i < 10*MaxTrackBatchSize; i++ {
+		events = append(events, &TrackEvent{
+			DistinctID: "13793",
+			EventName:  "Signed Up",
+			Event:      &Event{Properties: map[string]interface{}{"chunk": i / MaxTrackBatchSize}},
+		})
+	}
+
+	err := client.TrackBatch(context.TODO(), events)
+
+	var berr *ErrBatchFailed
+	if !errors.As(err, &berr) || len(berr.Errors) != 2 {
+		t.Fatalf("Error should be an *ErrBatchFailed with two errors: %v", err)
+	}
+	if want := []int{3 * MaxTrackBatchSize, 7 * MaxTrackBatchSize}; !reflect.DeepEqual(berr.Offsets, want) {
+		t.Errorf("Offsets returned %v, want %v", berr.Offsets, want)
+	}
+	if requests != 10 {
+		t.Errorf("TrackBatch made %d requests, want 10", requests)
+	}
+	if maxInFlight < 2 || maxInFlight > 4 {
+		t.Errorf("TrackBatch sent %d requests at the same time, want 2 to 4", maxInFlight)
+	}
+}
+
+func TestTrackBatchConcurrencyHonorsContext(t *testing.T) {
+	requests := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	cancelAfterFirst := func(req *http.Request, resp *http.Response, err error, attempt int, elapsed time.Duration) {
+		cancel()
+	}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithHook(cancelAfterFirst))
+
+	events := []*TrackEvent{}
+	for i := 0; i < 4*MaxTrackBatchSize; i++ {
+		events = append(events, &TrackEvent{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}})
+	}
+
+	err := client.TrackBatch(ctx, events)
+
+	var berr *ErrBatchFailed
+	if !errors.As(err, &berr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Error should be an *ErrBatchFailed wrapping context.Canceled: %v", err)
+	}
+	if want := []int{MaxTrackBatchSize}; !reflect.DeepEqual(berr.Offsets, want) {
+		t.Errorf("Offsets returned %v, want %v", berr.Offsets, want)
+	}
+	if requests != 1 {
+		t.Errorf("TrackBatch made %d requests, want 1", requests)
+	}
+}
+
+func TestNewWithRegion(t *testing.T) {
+	tests := map[Region]string{
+		RegionUS: "https://api.mixpanel.com",
+		RegionEU: "https://api-eu.mixpanel.com",
+		RegionIN: "https://api-in.mixpanel.com",
+	}
+
+	for region, want := range tests {
+		m := NewWithRegion("e3bc4100330c35722740fb8c6f5abddc", region).(*mixpanel)
+
+		if m.ApiURL != want {
+			t.Errorf("region %s returned %+v, want %+v", region, m.ApiURL, want)
+		}
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Increment(context.TODO(), "13793", map[string]int{
+		"Coins Gathered": 12,
+		"Lives":          -1,
+	})
+
+	want := "{\"$add\":{\"Coins Gathered\":12,\"Lives\":-1},\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/engage"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+
+	client.IncrementOne(context.TODO(), "13793", "Lives", 3)
+
+	want = "{\"$add\":{\"Lives\":3},\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestSetOnce(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.SetOnce(context.TODO(), "13793", map[string]interface{}{
+		"First Login": "2013-04-01T13:20:00",
+	})
+
+	want := "{\"$distinct_id\":\"13793\",\"$set_once\":{\"First Login\":\"2013-04-01T13:20:00\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/engage"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Append(context.TODO(), "13793", map[string]interface{}{
+		"Power Ups": "Bubble Lead",
+		"Levels":    3,
+	})
+
+	want := "{\"$append\":{\"Levels\":3,\"Power Ups\":\"Bubble Lead\"},\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/engage"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Union(context.TODO(), "13793", map[string][]interface{}{
+		"Items purchased": {"socks", "shirts"},
+		"Wishlist":        nil,
+	})
+
+	want := "{\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\",\"$union\":{\"Items purchased\":[\"socks\",\"shirts\"],\"Wishlist\":[]}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/engage"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Remove(context.TODO(), "13793", map[string]interface{}{
+		"Items purchased": "socks",
+	})
+
+	want := "{\"$distinct_id\":\"13793\",\"$remove\":{\"Items purchased\":\"socks\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/engage"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+}
+
+func TestUnset(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Unset(context.TODO(), "13793", []string{"Days Overdue", "Address"})
+
+	want := "{\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\",\"$unset\":[\"Days Overdue\",\"Address\"]}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/engage"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+
+	LastRequest = nil
+	if err := client.Unset(context.TODO(), "13793", nil); err == nil {
+		t.Error("Unset without properties should return an error")
+	}
+	if LastRequest != nil {
+		t.Error("Unset without properties should not send a request")
+	}
+}
+
+func TestDeleteProfile(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.DeleteProfile(context.TODO(), "13793")
+
+	want := "{\"$delete\":\"\",\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/engage"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+
+	client.DeleteProfileWithFlag(context.TODO(), "13793", true)
+
+	want = "{\"$delete\":\"\",\"$distinct_id\":\"13793\",\"$ignore_alias\":true,\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Alias(context.TODO(), "13793", "new-id")
+
+	want := "{\"event\":\"$create_alias\",\"properties\":{\"alias\":\"new-id\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/track"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+
+	LastRequest = nil
+	err := client.TrackBatch(context.TODO(), []*TrackEvent{
+		{DistinctID: "13793", EventName: "$create_alias", Event: &Event{}},
+	})
+	if err == nil {
+		t.Error("TrackBatch should reject $create_alias events")
+	}
+	if LastRequest != nil {
+		t.Error("TrackBatch should not send a request containing $create_alias events")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Merge(context.TODO(), "13793", "13794")
+
+	want := "{\"event\":\"$merge\",\"properties\":{\"$distinct_ids\":[\"13793\",\"13794\"],\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/import"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+
+	if user, _, _ := LastRequest.BasicAuth(); user != "mysecret" {
+		t.Errorf("basic auth user returned %+v, want %+v", user, "mysecret")
+	}
+
+	LastRequest = nil
+	noSecret := New("e3bc4100330c35722740fb8c6f5abddc", ts.URL)
+	if err := noSecret.Merge(context.TODO(), "13793", "13794"); err == nil {
+		t.Error("Merge without a secret should return an error")
+	}
+	if LastRequest != nil {
+		t.Error("Merge without a secret should not send a request")
+	}
+}
+
+func TestAliasBatch(t *testing.T) {
+	requests, fail := 0, false
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		if fail {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"code": 400, "error": "some data points in the request failed validation", "num_records_imported": 0, "status": "Bad Request"}`))
+			return
+		}
+		w.Write([]byte(`{"code": 200, "num_records_imported": 1, "status": "OK"}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL))
+
+	aliases := []AliasPair{}
+	for i := 0; i < MaxImportBatchSize; i++ {
+		aliases = append(aliases, AliasPair{DistinctID: "13793", Alias: "user-13793"})
+	}
+	aliases = append(aliases, AliasPair{DistinctID: "13794", Alias: "user-13794"})
+
+	if err := client.AliasBatch(context.TODO(), aliases); err != nil {
+		t.Errorf("AliasBatch returned an error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("AliasBatch made %d requests, want 2", requests)
+	}
+
+	want := "[{\"event\":\"$create_alias\",\"properties\":{\"alias\":\"user-13794\",\"distinct_id\":\"13794\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}]"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+	if path := LastRequest.URL.Path; path != "/import" {
+		t.Errorf("path returned %+v, want %+v", path, "/import")
+	}
+
+	requests, fail = 0, true
+	var berr *ErrBatchFailed
+	if err := client.AliasBatch(context.TODO(), aliases); !errors.As(err, &berr) || len(berr.Errors) != 2 {
+		t.Errorf("AliasBatch with failing requests returned %v, want an *ErrBatchFailed with 2 errors", err)
+	}
+
+	requests = 0
+	if err := client.AliasBatch(context.TODO(), []AliasPair{{DistinctID: "13793"}}); err == nil {
+		t.Error("AliasBatch with an empty alias should return an error")
+	}
+	noSecret := New("e3bc4100330c35722740fb8c6f5abddc", ts.URL)
+	if err := noSecret.AliasBatch(context.TODO(), aliases); err == nil {
+		t.Error("AliasBatch without a secret should return an error")
+	}
+	if requests != 0 {
+		t.Errorf("invalid AliasBatch calls made %d requests, want 0", requests)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL), WithCompression())
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	if encoding := LastRequest.Header.Get("Content-Encoding"); encoding != "" {
+		t.Errorf("small body was sent with Content-Encoding %q", encoding)
+	}
+
+	events := []*ImportEvent{}
+	for i := 0; i < 100; i++ {
+		events = append(events, &ImportEvent{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}})
+	}
+
+	client.ImportBatch(context.TODO(), events)
+
+	if encoding := LastRequest.Header.Get("Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("large body was sent with Content-Encoding %q, want gzip", encoding)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(LastPost))
+	if err != nil {
+		t.Fatalf("body is not gzipped: %v", err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("body is not gzipped: %v", err)
+	}
+
+	want := "[" + strings.Repeat("{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}},", 99) +
+		"{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}]"
+
+	if !reflect.DeepEqual(string(body), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			string(body), want)
+	}
+}
+
+func TestUpdateGroupBatch(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		w.WriteHeader(200)
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = New("e3bc4100330c35722740fb8c6f5abddc", ts.URL)
+
+	updates := []*GroupUpdate{}
+	for i := 0; i < 2*MaxGroupBatchSize; i++ {
+		updates = append(updates, &GroupUpdate{GroupID: "11", Update: &Update{
+			Operation:  "$set",
+			Properties: map[string]interface{}{"Plan": "Premium"},
+		}})
+	}
+	updates = append(updates, &GroupUpdate{GroupID: "12", Update: &Update{
+		Operation:  "$set",
+		Properties: map[string]interface{}{"Plan": "Free"},
+	}}, &GroupUpdate{GroupID: "12", Update: &Update{
+		Operation:  "$union",
+		Properties: map[string]interface{}{"Tags": []string{"new"}},
+	}})
+
+	if err := client.UpdateGroupBatch(context.TODO(), "company_id", updates); err != nil {
+		t.Errorf("UpdateGroupBatch returned an error: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("UpdateGroupBatch made %d requests, want 3", requests)
+	}
+
+	want := "[{\"$group_id\":\"12\",\"$group_key\":\"company_id\",\"$set\":{\"Plan\":\"Free\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}," +
+		"{\"$group_id\":\"12\",\"$group_key\":\"company_id\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\",\"$union\":{\"Tags\":[\"new\"]}}]"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/groups"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+}
+
+func TestGroupOperationHelpers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	tests := []struct {
+		call func() error
+		want string
+	}{
+		{
+			func() error {
+				return client.GroupSet(context.TODO(), "company_id", "11", map[string]interface{}{"Plan": "Premium"})
+			},
+			"{\"$group_id\":\"11\",\"$group_key\":\"company_id\",\"$set\":{\"Plan\":\"Premium\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}",
+		},
+		{
+			func() error {
+				return client.GroupSetOnce(context.TODO(), "company_id", "11", map[string]interface{}{"Plan": "Premium"})
+			},
+			"{\"$group_id\":\"11\",\"$group_key\":\"company_id\",\"$set_once\":{\"Plan\":\"Premium\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}",
+		},
+		{
+			func() error {
+				return client.GroupUnion(context.TODO(), "company_id", "11", map[string][]interface{}{"Tags": {"b2b"}, "Regions": nil})
+			},
+			"{\"$group_id\":\"11\",\"$group_key\":\"company_id\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\",\"$union\":{\"Regions\":[],\"Tags\":[\"b2b\"]}}",
+		},
+		{
+			func() error {
+				return client.GroupRemove(context.TODO(), "company_id", "11", map[string]interface{}{"Tags": "b2b"})
+			},
+			"{\"$group_id\":\"11\",\"$group_key\":\"company_id\",\"$remove\":{\"Tags\":\"b2b\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}",
+		},
+		{
+			func() error {
+				return client.GroupUnset(context.TODO(), "company_id", "11", []string{"Plan"})
+			},
+			"{\"$group_id\":\"11\",\"$group_key\":\"company_id\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\",\"$unset\":[\"Plan\"]}",
+		},
+		{
+			func() error {
+				return client.GroupDelete(context.TODO(), "company_id", "11")
+			},
+			"{\"$delete\":\"\",\"$group_id\":\"11\",\"$group_key\":\"company_id\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}",
+		},
+	}
+
+	for _, test := range tests {
+		test.call()
+
+		if !reflect.DeepEqual(decodeBody(), test.want) {
+			t.Errorf("Post body returned %+v, want %+v",
+				decodeBody(), test.want)
+		}
+
+		want := "/groups"
+		path := LastRequest.URL.Path
+
+		if !reflect.DeepEqual(path, want) {
+			t.Errorf("path returned %+v, want %+v",
+				path, want)
+		}
+	}
+
+	if err := client.GroupUnset(context.TODO(), "company_id", "11", nil); err == nil {
+		t.Error("GroupUnset without properties should return an error")
+	}
+}
+
+func TestVerboseImport(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(400)
+		w.Write([]byte(`{"code": 400, "error": "some data points in the request failed validation", "num_records_imported": 1999, "status": "Bad Request", "failed_records": [{"index": 5, "$insert_id": "13793-5", "field": "properties.time", "message": "'properties.time' is invalid: must be specified as seconds since epoch"}]}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL), WithVerboseImport())
+
+	events := []*ImportEvent{}
+	for i := 0; i < MaxImportBatchSize+10; i++ {
+		events = append(events, &ImportEvent{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}})
+	}
+
+	err := client.ImportBatch(context.TODO(), events)
+
+	var berr *ErrBatchFailed
+	if !errors.As(err, &berr) {
+		t.Fatalf("Error should be a *ErrBatchFailed: %v", err)
+	}
+	if len(berr.Errors) != 2 {
+		t.Fatalf("ErrBatchFailed carries %d errors, want 2", len(berr.Errors))
+	}
+
+	for i, wantIndex := range []int{5, MaxImportBatchSize + 5} {
+		var ierr *ErrImportFailed
+		if !errors.As(berr.Errors[i], &ierr) {
+			t.Fatalf("Error should be a *ErrImportFailed: %v", berr.Errors[i])
+		}
+
+		want := ImportResult{
+			NumRecordsImported: 1999,
+			Failed: []RecordError{{
+				Index:    wantIndex,
+				InsertID: "13793-5",
+				Field:    "properties.time",
+				Message:  "'properties.time' is invalid: must be specified as seconds since epoch",
+			}},
+		}
+		if !reflect.DeepEqual(ierr.Result, want) {
+			t.Errorf("Result returned %+v, want %+v", ierr.Result, want)
+		}
+	}
+
+	var terr *ErrTrackFailed
+	if !errors.As(err, &terr) || terr.HTTPCode != 400 {
+		t.Errorf("Error should wrap a *ErrTrackFailed: %v", err)
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 2 {
+			w.WriteHeader(429)
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithRetry(1, 0))
+
+	events := []*TrackEvent{}
+	for i := 0; i < 3*MaxTrackBatchSize; i++ {
+		events = append(events, &TrackEvent{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}})
+	}
+
+	err := client.TrackBatch(context.TODO(), events)
+
+	var rerr *RateLimitError
+	if !errors.As(err, &rerr) {
+		t.Errorf("Error should wrap a *RateLimitError: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("TrackBatch made %d requests, want 3", requests)
+	}
+}
+
+func TestUpdateBatch(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		LastRequest = r
+		LastPost, _ = io.ReadAll(r.Body)
+		w.WriteHeader(200)
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = New("e3bc4100330c35722740fb8c6f5abddc", ts.URL)
+
+	updates := []*ProfileUpdate{}
+	for i := 0; i < MaxEngageBatchSize; i++ {
+		updates = append(updates, &ProfileUpdate{DistinctID: "13793", Update: &Update{
+			Operation:  "$set",
+			Properties: map[string]interface{}{"Plan": "Premium"},
+		}})
+	}
+	updates = append(updates, &ProfileUpdate{DistinctID: "13794", Update: &Update{
+		Operation:  "$add",
+		Properties: map[string]interface{}{"Logins": 1},
+	}}, &ProfileUpdate{DistinctID: "13794", Update: &Update{
+		Operation:  "$union",
+		Properties: map[string]interface{}{"Tags": []string{"new"}},
+	}})
+
+	if err := client.UpdateBatch(context.TODO(), updates); err != nil {
+		t.Errorf("UpdateBatch returned an error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("UpdateBatch made %d requests, want 2", requests)
+	}
+
+	want := "[{\"$add\":{\"Logins\":1},\"$distinct_id\":\"13794\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}," +
+		"{\"$distinct_id\":\"13794\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\",\"$union\":{\"Tags\":[\"new\"]}}]"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/engage"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+
+	requests = 0
+	err := client.UpdateBatch(context.TODO(), []*ProfileUpdate{{DistinctID: "13793"}})
+	if err == nil || requests != 0 {
+		t.Errorf("UpdateBatch should reject nil updates without sending them: %v", err)
+	}
+}
+
+func TestUpdateGroupBatchValidation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	updates := []*GroupUpdate{}
+	for i := 0; i < MaxGroupBatchSize; i++ {
+		updates = append(updates, &GroupUpdate{GroupID: "11", Update: &Update{Operation: "$set"}})
+	}
+	updates = append(updates, &GroupUpdate{GroupID: "12"})
+
+	LastRequest = nil
+	err := client.UpdateGroupBatch(context.TODO(), "company_id", updates)
+
+	var merr *MixpanelError
+	if !errors.As(err, &merr) {
+		t.Errorf("Error should be a *MixpanelError: %v", err)
+	}
+	if LastRequest != nil {
+		t.Error("UpdateGroupBatch should not send anything when an update is nil")
+	}
+}
+
+func TestImportBatchResult(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(200)
+		if requests == 1 {
+			w.Write([]byte(`{"code": 200, "num_records_imported": 2000, "status": "OK"}`))
+			return
+		}
+		w.Write([]byte(`{"code": 200, "num_records_imported": 10, "status": "OK"}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL), WithVerboseImport())
+
+	events := []*ImportEvent{}
+	for i := 0; i < MaxImportBatchSize+10; i++ {
+		events = append(events, &ImportEvent{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}})
+	}
+
+	result, err := client.ImportBatchResult(context.TODO(), events)
+	if err != nil {
+		t.Fatalf("ImportBatchResult returned an error: %v", err)
+	}
+
+	want := &ImportResult{NumRecordsImported: MaxImportBatchSize + 10}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("ImportBatchResult returned %+v, want %+v", result, want)
+	}
+}
+
+func TestEncodeFormReusesBuffers(t *testing.T) {
+	m := NewClient("e3bc4100330c35722740fb8c6f5abddc").(*mixpanel)
+
+	want := func(params interface{}) string {
+		data, _ := json.Marshal(params)
+		return "data=" + base64.StdEncoding.EncodeToString(data)
+	}
+
+	large := map[string]interface{}{"event": "Signed Up", "properties": map[string]interface{}{"Notes": strings.Repeat("x", 4096)}}
+	small := map[string]interface{}{"event": "<b>", "properties": map[string]interface{}{"Plan": "Premium"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		params := large
+		if i%2 == 1 {
+			params = small
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			form, err := m.encodeForm(params, false)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(form) != want(params) {
+				t.Errorf("encodeForm returned %s, want %s", form, want(params))
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,53 @@
+// Package mixpanelotel traces the requests of mixpanel clients with
+// OpenTelemetry. It lives in its own module so that the mixpanel package
+// doesn't depend on OpenTelemetry.
+package mixpanelotel
+
+import (
+	"context"
+
+	"github.com/freshpaint-io/mixpanel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/freshpaint-io/mixpanel/mixpanelotel"
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a mixpanel.Tracer creating a client span named after the
+// api of each request, such as "mixpanel.track", using tp.
+func NewTracer(tp trace.TracerProvider) mixpanel.Tracer {
+	return &tracer{tracer: tp.Tracer(instrumentationName, trace.WithInstrumentationVersion(mixpanel.Version))}
+}
+
+// WithTracerProvider traces the requests of a client with spans created by
+// tp.
+func WithTracerProvider(tp trace.TracerProvider) mixpanel.Option {
+	return mixpanel.WithTracer(NewTracer(tp))
+}
+
+func (t *tracer) StartSpan(ctx context.Context, operation, url string, records int) (context.Context, func(int, error)) {
+	ctx, span := t.tracer.Start(ctx, "mixpanel."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("mixpanel.operation", operation),
+			attribute.String("url.full", url),
+			attribute.Int("mixpanel.records", records),
+		),
+	)
+
+	return ctx, func(statusCode int, err error) {
+		if statusCode != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,59 @@
+package mixpanelotel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freshpaint-io/mixpanel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithTracerProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/engage" {
+			w.WriteHeader(401)
+			w.Write([]byte(`{"error": "Invalid API secret", "status": 0}`))
+			return
+		}
+		w.Write([]byte(`{"error": "", "status": 1}`))
+	}))
+	defer ts.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	client := mixpanel.NewClient("e3bc4100330c35722740fb8c6f5abddc", mixpanel.WithBaseURL(ts.URL), WithTracerProvider(tp))
+
+	client.Track(context.TODO(), "13793", "Signed Up", &mixpanel.Event{})
+	client.UpdateUser(context.TODO(), "13793", &mixpanel.Update{Operation: "$set", Properties: map[string]interface{}{}})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans were recorded, want 2", len(spans))
+	}
+
+	track := spans[0]
+	if track.Name != "mixpanel.track" {
+		t.Errorf("span name returned %+v, want %+v", track.Name, "mixpanel.track")
+	}
+	attrs := attribute.NewSet(track.Attributes...)
+	if v, _ := attrs.Value("http.response.status_code"); v.AsInt64() != 200 {
+		t.Errorf("status code returned %+v, want 200", v.AsInt64())
+	}
+	if v, _ := attrs.Value("mixpanel.records"); v.AsInt64() != 1 {
+		t.Errorf("records returned %+v, want 1", v.AsInt64())
+	}
+	if track.Status.Code != codes.Unset {
+		t.Errorf("status returned %+v, want unset", track.Status)
+	}
+
+	engage := spans[1]
+	if engage.Name != "mixpanel.engage" || engage.Status.Code != codes.Error {
+		t.Errorf("engage span should have failed: %+v %+v", engage.Name, engage.Status)
+	}
+}
@@ -0,0 +1,79 @@
+// Package mixpanelprom exports Prometheus metrics of the requests of mixpanel
+// clients. It lives in its own module so that the mixpanel package doesn't
+// depend on Prometheus.
+package mixpanelprom
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/freshpaint-io/mixpanel"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics collects the metrics of the requests of the clients it was added
+// to with Option. The collected metrics are:
+//
+//	mixpanel_requests_total{endpoint,status}
+//	mixpanel_request_duration_seconds{endpoint}
+//	mixpanel_events_sent_total{endpoint}
+//
+// where endpoint is the api of the request, such as "track" or "import", and
+// status the HTTP status code of its response, or "error" when none was
+// received.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	events   *prometheus.CounterVec
+}
+
+// NewMetrics creates the metrics and registers them with registerer.
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mixpanel_requests_total",
+			Help: "Number of requests sent to mixpanel, by endpoint and status.",
+		}, []string{"endpoint", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mixpanel_request_duration_seconds",
+			Help:    "Duration of requests sent to mixpanel, retries included.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"endpoint"}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mixpanel_events_sent_total",
+			Help: "Number of events and updates successfully sent to mixpanel.",
+		}, []string{"endpoint"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.requests, m.duration, m.events} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Option adds the metrics to a client.
+func (m *Metrics) Option() mixpanel.Option {
+	return mixpanel.WithTracer(m)
+}
+
+// StartSpan implements mixpanel.Tracer.
+func (m *Metrics) StartSpan(ctx context.Context, operation, url string, records int) (context.Context, func(int, error)) {
+	start := time.Now()
+
+	return ctx, func(statusCode int, err error) {
+		status := "error"
+		if statusCode != 0 {
+			status = strconv.Itoa(statusCode)
+		}
+
+		m.requests.WithLabelValues(operation, status).Inc()
+		m.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		if err == nil {
+			m.events.WithLabelValues(operation).Add(float64(records))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package mixpanelprom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freshpaint-io/mixpanel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/engage" {
+			w.WriteHeader(401)
+			w.Write([]byte(`{"error": "Invalid API secret", "status": 0}`))
+			return
+		}
+		w.Write([]byte(`{"error": "", "status": 1}`))
+	}))
+	defer ts.Close()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := mixpanel.NewClient("e3bc4100330c35722740fb8c6f5abddc", mixpanel.WithBaseURL(ts.URL), metrics.Option())
+
+	client.TrackBatch(context.TODO(), []*mixpanel.TrackEvent{
+		{DistinctID: "13793", EventName: "Signed Up", Event: &mixpanel.Event{}},
+		{DistinctID: "13794", EventName: "Signed Up", Event: &mixpanel.Event{}},
+	})
+	client.UpdateUser(context.TODO(), "13793", &mixpanel.Update{Operation: "$set", Properties: map[string]interface{}{}})
+
+	if got := testutil.ToFloat64(metrics.requests.WithLabelValues("track", "200")); got != 1 {
+		t.Errorf("track requests returned %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.requests.WithLabelValues("engage", "401")); got != 1 {
+		t.Errorf("engage requests returned %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.events.WithLabelValues("track")); got != 2 {
+		t.Errorf("track events returned %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.events.WithLabelValues("engage")); got != 0 {
+		t.Errorf("engage events returned %v, want 0", got)
+	}
+	if got := testutil.CollectAndCount(metrics.duration); got != 2 {
+		t.Errorf("duration series returned %v, want 2", got)
+	}
+}
@@ -0,0 +1,70 @@
+package mixpaneltest
+
+import (
+	"reflect"
+	"testing"
+)
+
+// AssertTracked fails the test unless an event with the given name was
+// recorded whose properties include props.
+func (r *Recorder) AssertTracked(t testing.TB, name string, props map[string]interface{}) {
+	t.Helper()
+
+	events := r.EventsNamed(name)
+	if len(events) == 0 {
+		t.Errorf("mixpaneltest: no %q event was tracked", name)
+		return
+	}
+
+	for _, event := range events {
+		if containsProperties(event.Event.Properties, props) {
+			return
+		}
+	}
+
+	t.Errorf("mixpaneltest: no %q event was tracked with properties %v, got %d event(s) with other properties", name, props, len(events))
+}
+
+// AssertNotTracked fails the test if an event with the given name was
+// recorded.
+func (r *Recorder) AssertNotTracked(t testing.TB, name string) {
+	t.Helper()
+
+	if events := r.EventsNamed(name); len(events) > 0 {
+		t.Errorf("mixpaneltest: %d %q event(s) were tracked, want none", len(events), name)
+	}
+}
+
+// AssertEventCount fails the test unless exactly n events with the given
+// name were recorded.
+func (r *Recorder) AssertEventCount(t testing.TB, name string, n int) {
+	t.Helper()
+
+	if events := r.EventsNamed(name); len(events) != n {
+		t.Errorf("mixpaneltest: %d %q event(s) were tracked, want %d", len(events), name, n)
+	}
+}
+
+// AssertProfileUpdated fails the test unless the profile of distinctId was
+// updated with the given operation and properties including props.
+func (r *Recorder) AssertProfileUpdated(t testing.TB, distinctId, operation string, props map[string]interface{}) {
+	t.Helper()
+
+	for _, update := range r.ProfileUpdates(distinctId) {
+		if update.Update.Operation == operation && containsProperties(update.Update.Properties, props) {
+			return
+		}
+	}
+
+	t.Errorf("mixpaneltest: the profile of %q was not updated with %s %v", distinctId, operation, props)
+}
+
+func containsProperties(properties, props map[string]interface{}) bool {
+	for key, want := range props {
+		got, ok := properties[key]
+		if !ok || !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,520 @@
+// Package mixpaneltest provides a fake mixpanel client for the tests of code
+// using the mixpanel package.
+package mixpaneltest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/freshpaint-io/mixpanel"
+)
+
+// A recorded Track or Import call
+type Event struct {
+	DistinctID string
+	Name       string
+	Event      mixpanel.Event
+
+	// Whether the event was sent with the import api.
+	Imported bool
+}
+
+// A recorded update of a user profile. Operations that don't take properties,
+// such as $unset and $delete, record the affected property names as keys of
+// Update.Properties with nil values.
+type ProfileUpdate struct {
+	DistinctID string
+	Update     mixpanel.Update
+}
+
+// A recorded update of a group profile
+type GroupUpdate struct {
+	GroupKey string
+	GroupID  string
+	Update   mixpanel.Update
+}
+
+// A recorded Alias or Merge call
+type Identity struct {
+	DistinctID string
+	OtherID    string
+
+	// Whether the ids were merged rather than aliased.
+	Merged bool
+}
+
+// Recorder implements mixpanel.Mixpanel by recording every call in memory.
+// It is safe for concurrent use. The zero value is ready to use.
+type Recorder struct {
+	mixpanel.NopCloser
+
+	// Schemas checked by TrackTyped, when set
+	Registry *mixpanel.EventRegistry
+
+	mu         sync.Mutex
+	super      map[string]interface{}
+	events     []Event
+	profiles   []ProfileUpdate
+	groups     []GroupUpdate
+	identities []Identity
+}
+
+var _ mixpanel.Mixpanel = &Recorder{}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Events returns the recorded events, in the order they were sent.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Event(nil), r.events...)
+}
+
+// EventsNamed returns the recorded events with the given name.
+func (r *Recorder) EventsNamed(name string) []Event {
+	var events []Event
+	for _, event := range r.Events() {
+		if event.Name == name {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// ProfileUpdates returns the recorded updates of the profile of distinctId.
+func (r *Recorder) ProfileUpdates(distinctId string) []ProfileUpdate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var updates []ProfileUpdate
+	for _, update := range r.profiles {
+		if update.DistinctID == distinctId {
+			updates = append(updates, update)
+		}
+	}
+	return updates
+}
+
+// GroupUpdates returns the recorded updates of the given group.
+func (r *Recorder) GroupUpdates(groupKey, groupId string) []GroupUpdate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var updates []GroupUpdate
+	for _, update := range r.groups {
+		if update.GroupKey == groupKey && update.GroupID == groupId {
+			updates = append(updates, update)
+		}
+	}
+	return updates
+}
+
+// Identities returns the recorded aliases and merges.
+func (r *Recorder) Identities() []Identity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Identity(nil), r.identities...)
+}
+
+// SuperProperties returns the properties of the last SetSuperProperties
+// call. They are not merged into the recorded events.
+func (r *Recorder) SuperProperties() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.super
+}
+
+// SetSuperProperties records props, see SuperProperties.
+func (r *Recorder) SetSuperProperties(props map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.super = props
+}
+
+// Reset forgets all recorded calls.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = nil
+	r.profiles = nil
+	r.groups = nil
+	r.identities = nil
+}
+
+func (r *Recorder) recordEvent(distinctId, eventName string, e *mixpanel.Event, imported bool) {
+	event := Event{DistinctID: distinctId, Name: eventName, Imported: imported}
+	if e != nil {
+		event.Event = *e
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+func (r *Recorder) recordProfile(distinctId string, u mixpanel.Update) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.profiles = append(r.profiles, ProfileUpdate{DistinctID: distinctId, Update: u})
+	return nil
+}
+
+func (r *Recorder) recordGroup(groupKey, groupId string, u mixpanel.Update) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.groups = append(r.groups, GroupUpdate{GroupKey: groupKey, GroupID: groupId, Update: u})
+	return nil
+}
+
+func (r *Recorder) Track(ctx context.Context, distinctId, eventName string, e *mixpanel.Event) error {
+	r.recordEvent(distinctId, eventName, e, false)
+	return nil
+}
+
+func (r *Recorder) TrackStruct(ctx context.Context, distinctID, eventName string, props interface{}) error {
+	properties, err := mixpanel.StructProperties(props)
+	if err != nil {
+		return err
+	}
+
+	return r.Track(ctx, distinctID, eventName, &mixpanel.Event{Properties: properties})
+}
+
+// TrackTyped records an event like Track, once checked against Registry
+// when set.
+func (r *Recorder) TrackTyped(ctx context.Context, distinctID, eventName string, e *mixpanel.Event) error {
+	if r.Registry != nil {
+		if err := r.Registry.Validate(eventName, e.Properties); err != nil {
+			return err
+		}
+	}
+
+	return r.Track(ctx, distinctID, eventName, e)
+}
+
+func (r *Recorder) Import(ctx context.Context, distinctId, eventName string, e *mixpanel.Event) error {
+	r.recordEvent(distinctId, eventName, e, true)
+	return nil
+}
+
+func (r *Recorder) TrackBatch(ctx context.Context, events []*mixpanel.TrackEvent) error {
+	for _, event := range events {
+		r.recordEvent(event.DistinctID, event.EventName, event.Event, false)
+	}
+	return nil
+}
+
+func (r *Recorder) ImportBatch(ctx context.Context, events []*mixpanel.ImportEvent) error {
+	for _, event := range events {
+		r.recordEvent(event.DistinctID, event.EventName, event.Event, true)
+	}
+	return nil
+}
+
+func (r *Recorder) ImportBatchAt(ctx context.Context, events []*mixpanel.ImportEvent, at time.Time) error {
+	return r.ImportBatch(ctx, mixpanel.EventsAt(events, at))
+}
+
+func (r *Recorder) ImportBatchResult(ctx context.Context, events []*mixpanel.ImportEvent) (*mixpanel.ImportResult, error) {
+	r.ImportBatch(ctx, events)
+	return &mixpanel.ImportResult{NumRecordsImported: len(events)}, nil
+}
+
+// ImportStream records the events of every line of the stream, and fails on
+// the first line which can't be decoded.
+func (r *Recorder) ImportStream(ctx context.Context, stream io.Reader) (*mixpanel.ImportResult, error) {
+	result := &mixpanel.ImportResult{}
+	decoder := json.NewDecoder(stream)
+	for {
+		var line struct {
+			Event      string                 `json:"event"`
+			Properties map[string]interface{} `json:"properties"`
+		}
+		if err := decoder.Decode(&line); err == io.EOF {
+			return result, nil
+		} else if err != nil {
+			return result, err
+		}
+
+		distinctID, _ := line.Properties["distinct_id"].(string)
+		r.Import(ctx, distinctID, line.Event, &mixpanel.Event{Properties: line.Properties})
+		result.NumRecordsImported++
+	}
+}
+
+func (r *Recorder) Update(ctx context.Context, distinctId string, u *mixpanel.Update) error {
+	return r.UpdateUser(ctx, distinctId, u)
+}
+
+func (r *Recorder) UpdateUser(ctx context.Context, distinctId string, u *mixpanel.Update) error {
+	return r.recordProfile(distinctId, *u)
+}
+
+func (r *Recorder) UpdateBatch(ctx context.Context, updates []*mixpanel.ProfileUpdate) error {
+	for _, update := range updates {
+		r.recordProfile(update.DistinctID, *update.Update)
+	}
+	return nil
+}
+
+func (r *Recorder) SetOnce(ctx context.Context, distinctId string, props map[string]interface{}) error {
+	return r.recordProfile(distinctId, mixpanel.Update{Operation: "$set_once", Properties: props})
+}
+
+func (r *Recorder) Append(ctx context.Context, distinctId string, props map[string]interface{}) error {
+	return r.recordProfile(distinctId, mixpanel.Update{Operation: "$append", Properties: props})
+}
+
+func (r *Recorder) Union(ctx context.Context, distinctId string, props map[string][]interface{}) error {
+	return r.recordProfile(distinctId, mixpanel.Update{Operation: "$union", Properties: listProperties(props)})
+}
+
+func (r *Recorder) Remove(ctx context.Context, distinctId string, props map[string]interface{}) error {
+	return r.recordProfile(distinctId, mixpanel.Update{Operation: "$remove", Properties: props})
+}
+
+func (r *Recorder) Unset(ctx context.Context, distinctId string, properties []string) error {
+	return r.recordProfile(distinctId, mixpanel.Update{Operation: "$unset", Properties: nameProperties(properties)})
+}
+
+func (r *Recorder) DeleteProfile(ctx context.Context, distinctId string) error {
+	return r.DeleteProfileWithFlag(ctx, distinctId, false)
+}
+
+func (r *Recorder) DeleteProfileWithFlag(ctx context.Context, distinctId string, ignoreAlias bool) error {
+	return r.recordProfile(distinctId, mixpanel.Update{Operation: "$delete", IgnoreAlias: ignoreAlias})
+}
+
+func (r *Recorder) Increment(ctx context.Context, distinctId string, props map[string]int) error {
+	properties := map[string]interface{}{}
+	for key, value := range props {
+		properties[key] = value
+	}
+	return r.recordProfile(distinctId, mixpanel.Update{Operation: "$add", Properties: properties})
+}
+
+func (r *Recorder) IncrementOne(ctx context.Context, distinctId, prop string, delta int) error {
+	return r.Increment(ctx, distinctId, map[string]int{prop: delta})
+}
+
+func (r *Recorder) UpdateGroup(ctx context.Context, groupKey, groupId string, u *mixpanel.Update) error {
+	return r.recordGroup(groupKey, groupId, *u)
+}
+
+func (r *Recorder) GroupSet(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error {
+	return r.recordGroup(groupKey, groupId, mixpanel.Update{Operation: "$set", Properties: props})
+}
+
+func (r *Recorder) SetProfile(ctx context.Context, distinctID string, p mixpanel.Profile, extra map[string]interface{}) error {
+	return r.recordProfile(distinctID, mixpanel.Update{Operation: "$set", Properties: p.Properties(extra)})
+}
+
+func (r *Recorder) SetLocation(ctx context.Context, distinctID string, lat, lng float64) error {
+	props, err := mixpanel.LocationProperties(lat, lng)
+	if err != nil {
+		return err
+	}
+
+	return r.recordProfile(distinctID, mixpanel.Update{Operation: "$set", Properties: props})
+}
+
+func (r *Recorder) TrackCharge(ctx context.Context, distinctID string, amount float64, at time.Time, props map[string]interface{}) error {
+	return r.recordProfile(distinctID, mixpanel.Update{
+		Operation:  "$append",
+		Properties: map[string]interface{}{"$transactions": mixpanel.ChargeProperties(amount, at, props)},
+	})
+}
+
+func (r *Recorder) GroupSetOnce(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error {
+	return r.recordGroup(groupKey, groupId, mixpanel.Update{Operation: "$set_once", Properties: props})
+}
+
+func (r *Recorder) GroupUnion(ctx context.Context, groupKey, groupId string, props map[string][]interface{}) error {
+	return r.recordGroup(groupKey, groupId, mixpanel.Update{Operation: "$union", Properties: listProperties(props)})
+}
+
+func (r *Recorder) GroupRemove(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error {
+	return r.recordGroup(groupKey, groupId, mixpanel.Update{Operation: "$remove", Properties: props})
+}
+
+func (r *Recorder) GroupUnset(ctx context.Context, groupKey, groupId string, properties []string) error {
+	return r.recordGroup(groupKey, groupId, mixpanel.Update{Operation: "$unset", Properties: nameProperties(properties)})
+}
+
+func (r *Recorder) GroupDelete(ctx context.Context, groupKey, groupId string) error {
+	return r.recordGroup(groupKey, groupId, mixpanel.Update{Operation: "$delete"})
+}
+
+func (r *Recorder) UpdateGroupBatch(ctx context.Context, groupKey string, updates []*mixpanel.GroupUpdate) error {
+	for _, update := range updates {
+		key := groupKey
+		if update.GroupKey != "" {
+			key = update.GroupKey
+		}
+		r.recordGroup(key, update.GroupID, *update.Update)
+	}
+	return nil
+}
+
+func (r *Recorder) Merge(ctx context.Context, distinctId1, distinctId2 string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.identities = append(r.identities, Identity{DistinctID: distinctId1, OtherID: distinctId2, Merged: true})
+	return nil
+}
+
+func (r *Recorder) Alias(ctx context.Context, distinctId, newId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.identities = append(r.identities, Identity{DistinctID: distinctId, OtherID: newId})
+	return nil
+}
+
+// AliasBatch records every alias like Alias.
+func (r *Recorder) AliasBatch(ctx context.Context, aliases []mixpanel.AliasPair) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pair := range aliases {
+		r.identities = append(r.identities, Identity{DistinctID: pair.DistinctID, OtherID: pair.Alias})
+	}
+	return nil
+}
+
+// Export always fails, since the Recorder doesn't store exportable events.
+func (r *Recorder) Export(ctx context.Context, params mixpanel.ExportParams) (*mixpanel.ExportReader, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support exports")
+}
+
+// JQL always fails, since the Recorder can't run scripts.
+func (r *Recorder) JQL(ctx context.Context, script string, params map[string]interface{}) ([]json.RawMessage, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support JQL")
+}
+
+// Segmentation always fails, use Events to inspect recorded events.
+func (r *Recorder) Segmentation(ctx context.Context, params mixpanel.SegmentationParams) (*mixpanel.SegmentationResult, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support segmentation queries")
+}
+
+// Funnel always fails, use Events to inspect recorded events.
+func (r *Recorder) Funnel(ctx context.Context, funnelID int, params mixpanel.FunnelParams) (*mixpanel.FunnelResult, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support funnel queries")
+}
+
+// ListFunnels always fails, since the Recorder doesn't save funnels.
+func (r *Recorder) ListFunnels(ctx context.Context) ([]mixpanel.FunnelInfo, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support funnel queries")
+}
+
+// Retention always fails, use Events to inspect recorded events.
+func (r *Recorder) Retention(ctx context.Context, params mixpanel.RetentionParams) (*mixpanel.RetentionResult, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support retention queries")
+}
+
+// Insights always fails, since the Recorder doesn't save reports.
+func (r *Recorder) Insights(ctx context.Context, bookmarkID int) (*mixpanel.InsightsResult, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support insights queries")
+}
+
+// TopEvents always fails, use Events to inspect recorded events.
+func (r *Recorder) TopEvents(ctx context.Context, params mixpanel.TopEventsParams) (*mixpanel.TopEventsResult, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support event queries")
+}
+
+// EventNames always fails, use Events to inspect recorded events.
+func (r *Recorder) EventNames(ctx context.Context, params mixpanel.TopEventsParams) ([]string, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support event queries")
+}
+
+// QueryEngage always fails, use ProfileUpdates to inspect recorded updates.
+func (r *Recorder) QueryEngage(ctx context.Context, params mixpanel.EngageQuery) (*mixpanel.EngageResults, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support engage queries")
+}
+
+// Reconcile always fails, use ProfileUpdates to inspect recorded updates.
+func (r *Recorder) Reconcile(ctx context.Context, distinctIDs []string, expected map[string]map[string]interface{}) ([]mixpanel.Mismatch, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support engage queries")
+}
+
+// CreateDeletionTask always fails, since the Recorder doesn't run tasks.
+func (r *Recorder) CreateDeletionTask(ctx context.Context, distinctIDs []string, opts mixpanel.DeletionOpts) (string, error) {
+	return "", errors.New("mixpaneltest: Recorder does not support deletion tasks")
+}
+
+// GetDeletionStatus always fails, since the Recorder doesn't run tasks.
+func (r *Recorder) GetDeletionStatus(ctx context.Context, taskID string) (*mixpanel.DeletionStatus, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support deletion tasks")
+}
+
+// CreateRetrievalTask always fails, since the Recorder doesn't run tasks.
+func (r *Recorder) CreateRetrievalTask(ctx context.Context, distinctIDs []string) (string, error) {
+	return "", errors.New("mixpaneltest: Recorder does not support retrieval tasks")
+}
+
+// GetRetrievalStatus always fails, since the Recorder doesn't run tasks.
+func (r *Recorder) GetRetrievalStatus(ctx context.Context, taskID string) (*mixpanel.RetrievalStatus, error) {
+	return nil, errors.New("mixpaneltest: Recorder does not support retrieval tasks")
+}
+
+// ReplaceLookupTable always fails, since the Recorder doesn't store tables.
+func (r *Recorder) ReplaceLookupTable(ctx context.Context, tableID string, csv io.Reader) error {
+	return errors.New("mixpaneltest: Recorder does not support lookup tables")
+}
+
+// EncodeTrack always fails, since the Recorder has no token to encode.
+func (r *Recorder) EncodeTrack(distinctID, eventName string, e *mixpanel.Event) (string, error) {
+	return "", errors.New("mixpaneltest: Recorder does not support encoding")
+}
+
+// EncodeImport always fails, since the Recorder has no token to encode.
+func (r *Recorder) EncodeImport(distinctID, eventName string, e *mixpanel.Event) (string, error) {
+	return "", errors.New("mixpaneltest: Recorder does not support encoding")
+}
+
+// EncodeUpdate always fails, since the Recorder has no token to encode.
+func (r *Recorder) EncodeUpdate(distinctID string, u *mixpanel.Update) (string, error) {
+	return "", errors.New("mixpaneltest: Recorder does not support encoding")
+}
+
+// EncodeGroupUpdate always fails, since the Recorder has no token to encode.
+func (r *Recorder) EncodeGroupUpdate(groupKey, groupID string, u *mixpanel.Update) (string, error) {
+	return "", errors.New("mixpaneltest: Recorder does not support encoding")
+}
+
+// Validate always succeeds, since the Recorder has no credentials to check.
+func (r *Recorder) Validate(ctx context.Context) error {
+	return nil
+}
+
+func listProperties(props map[string][]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for key, values := range props {
+		properties[key] = values
+	}
+	return properties
+}
+
+func nameProperties(names []string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, name := range names {
+		properties[name] = nil
+	}
+	return properties
+}
@@ -0,0 +1,86 @@
+package mixpaneltest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/freshpaint-io/mixpanel"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+
+	r.Track(context.TODO(), "13793", "Signed Up", &mixpanel.Event{
+		Properties: map[string]interface{}{"Referred By": "Friend", "Plan": "Free"},
+	})
+	r.ImportBatch(context.TODO(), []*mixpanel.ImportEvent{
+		{DistinctID: "13794", EventName: "Logged In", Event: &mixpanel.Event{}},
+	})
+	r.SetOnce(context.TODO(), "13793", map[string]interface{}{"First Login": "2013-04-01"})
+	r.Unset(context.TODO(), "13793", []string{"Address"})
+	r.GroupSet(context.TODO(), "company_id", "11", map[string]interface{}{"Plan": "Premium"})
+	r.Alias(context.TODO(), "13793", "new-id")
+
+	if n := len(r.Events()); n != 2 {
+		t.Errorf("Events returned %d events, want 2", n)
+	}
+	if events := r.EventsNamed("Logged In"); len(events) != 1 || !events[0].Imported {
+		t.Errorf("EventsNamed returned %+v, want one imported event", events)
+	}
+
+	r.AssertTracked(t, "Signed Up", map[string]interface{}{"Referred By": "Friend"})
+	r.AssertNotTracked(t, "Logged Out")
+	r.AssertEventCount(t, "Signed Up", 1)
+	r.AssertProfileUpdated(t, "13793", "$set_once", map[string]interface{}{"First Login": "2013-04-01"})
+	r.AssertProfileUpdated(t, "13793", "$unset", map[string]interface{}{"Address": nil})
+
+	if updates := r.GroupUpdates("company_id", "11"); len(updates) != 1 || updates[0].Update.Operation != "$set" {
+		t.Errorf("GroupUpdates returned %+v, want one $set update", updates)
+	}
+
+	want := []Identity{{DistinctID: "13793", OtherID: "new-id"}}
+	if identities := r.Identities(); !reflect.DeepEqual(identities, want) {
+		t.Errorf("Identities returned %+v, want %+v", identities, want)
+	}
+
+	r.Reset()
+	if n := len(r.Events()); n != 0 {
+		t.Errorf("Events returned %d events after Reset, want 0", n)
+	}
+}
+
+func TestRecorderAssertionsFail(t *testing.T) {
+	r := NewRecorder()
+	r.Track(context.TODO(), "13793", "Signed Up", &mixpanel.Event{
+		Properties: map[string]interface{}{"Referred By": "Friend"},
+	})
+
+	tests := map[string]func(t testing.TB){
+		"missing event":      func(t testing.TB) { r.AssertTracked(t, "Logged In", nil) },
+		"other properties":   func(t testing.TB) { r.AssertTracked(t, "Signed Up", map[string]interface{}{"Referred By": "Ad"}) },
+		"unexpected event":   func(t testing.TB) { r.AssertNotTracked(t, "Signed Up") },
+		"wrong count":        func(t testing.TB) { r.AssertEventCount(t, "Signed Up", 2) },
+		"no profile updates": func(t testing.TB) { r.AssertProfileUpdated(t, "13793", "$set", nil) },
+	}
+
+	for name, assert := range tests {
+		ft := &failureRecorder{TB: t}
+		assert(ft)
+		if !ft.failed {
+			t.Errorf("%s: the assertion did not fail", name)
+		}
+	}
+}
+
+// failureRecorder records failures instead of failing the test.
+type failureRecorder struct {
+	testing.TB
+	failed bool
+}
+
+func (f *failureRecorder) Helper() {}
+
+func (f *failureRecorder) Errorf(format string, args ...interface{}) {
+	f.failed = true
+}
@@ -2,15 +2,26 @@ package mixpanel
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"reflect"
 	"time"
 )
 
 // Mocked version of Mixpanel which can be used in unit tests.
 type Mock struct {
+	NopCloser
+
 	// All People identified, mapped by distinctId
 	People map[string]*MockPeople
+
+	// Schemas checked by TrackTyped, when set
+	Registry *EventRegistry
+
+	// Properties set with SetSuperProperties
+	SuperProperties map[string]interface{}
 }
 
 func NewMock() *Mock {
@@ -49,6 +60,29 @@ func (m *Mock) Track(ctx context.Context, distinctId, eventName string, e *Event
 	return nil
 }
 
+func (m *Mock) TrackStruct(ctx context.Context, distinctID, eventName string, props interface{}) error {
+	properties, err := StructProperties(props)
+	if err != nil {
+		return err
+	}
+
+	return m.Track(ctx, distinctID, eventName, &Event{Properties: properties})
+}
+
+func (m *Mock) TrackTyped(ctx context.Context, distinctID, eventName string, e *Event) error {
+	if m.Registry != nil {
+		if err := m.Registry.Validate(eventName, e.Properties); err != nil {
+			return err
+		}
+	}
+
+	return m.Track(ctx, distinctID, eventName, e)
+}
+
+func (m *Mock) SetSuperProperties(props map[string]interface{}) {
+	m.SuperProperties = props
+}
+
 func (m *Mock) Import(ctx context.Context, distinctId, eventName string, e *Event) error {
 	p := m.people(distinctId)
 	p.Events = append(p.Events, MockEvent{
@@ -58,6 +92,15 @@ func (m *Mock) Import(ctx context.Context, distinctId, eventName string, e *Even
 	return nil
 }
 
+func (m *Mock) TrackBatch(ctx context.Context, events []*TrackEvent) error {
+	for _, event := range events {
+		if err := m.Track(ctx, event.DistinctID, event.EventName, event.Event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type MockPeople struct {
 	Properties map[string]interface{}
 	Time       *time.Time
@@ -109,26 +152,343 @@ func (m *Mock) UpdateUser(ctx context.Context, distinctId string, u *Update) err
 	}
 
 	switch u.Operation {
-	case "$set", "$set_once":
+	case "$set":
 		for key, val := range u.Properties {
 			p.Properties[key] = val
 		}
+	case "$set_once":
+		for key, val := range u.Properties {
+			if _, ok := p.Properties[key]; !ok {
+				p.Properties[key] = val
+			}
+		}
+	case "$add":
+		for key, val := range u.Properties {
+			delta, ok := val.(int)
+			if !ok {
+				return errors.New("mixpanel.Mock only supports int values for the $add operation")
+			}
+			current, _ := p.Properties[key].(int)
+			p.Properties[key] = current + delta
+		}
+	case "$append":
+		for key, val := range u.Properties {
+			list, _ := p.Properties[key].([]interface{})
+			p.Properties[key] = append(list, val)
+		}
+	case "$union":
+		for key, val := range u.Properties {
+			values, _ := val.([]interface{})
+			list, _ := p.Properties[key].([]interface{})
+			for _, value := range values {
+				if !mockContains(list, value) {
+					list = append(list, value)
+				}
+			}
+			if list == nil {
+				list = []interface{}{}
+			}
+			p.Properties[key] = list
+		}
+	case "$remove":
+		for key, val := range u.Properties {
+			list, _ := p.Properties[key].([]interface{})
+			kept := []interface{}{}
+			for _, v := range list {
+				if !reflect.DeepEqual(v, val) {
+					kept = append(kept, v)
+				}
+			}
+			p.Properties[key] = kept
+		}
 	default:
-		return errors.New("mixpanel.Mock only supports the $set and $set_once operations")
+		return fmt.Errorf("mixpanel.Mock does not support the %s operation", u.Operation)
+	}
+
+	return nil
+}
+
+func (m *Mock) UpdateBatch(ctx context.Context, updates []*ProfileUpdate) error {
+	for _, update := range updates {
+		if err := m.UpdateUser(ctx, update.DistinctID, update.Update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mock) SetOnce(ctx context.Context, distinctId string, props map[string]interface{}) error {
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$set_once",
+		Properties: props,
+	})
+}
+
+func (m *Mock) Append(ctx context.Context, distinctId string, props map[string]interface{}) error {
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$append",
+		Properties: props,
+	})
+}
+
+func (m *Mock) Union(ctx context.Context, distinctId string, props map[string][]interface{}) error {
+	properties := map[string]interface{}{}
+	for key, values := range props {
+		properties[key] = values
+	}
+
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$union",
+		Properties: properties,
+	})
+}
+
+func (m *Mock) Remove(ctx context.Context, distinctId string, props map[string]interface{}) error {
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$remove",
+		Properties: props,
+	})
+}
+
+func (m *Mock) Unset(ctx context.Context, distinctId string, properties []string) error {
+	if len(properties) == 0 {
+		return errors.New("mixpanel.Mock requires at least one property to unset")
 	}
 
+	p := m.people(distinctId)
+	for _, key := range properties {
+		delete(p.Properties, key)
+	}
+	return nil
+}
+
+func (m *Mock) DeleteProfile(ctx context.Context, distinctId string) error {
+	return m.DeleteProfileWithFlag(ctx, distinctId, false)
+}
+
+func (m *Mock) DeleteProfileWithFlag(ctx context.Context, distinctId string, ignoreAlias bool) error {
+	delete(m.People, distinctId)
 	return nil
 }
 
+func mockContains(list []interface{}, value interface{}) bool {
+	for _, v := range list {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Mock) Increment(ctx context.Context, distinctId string, props map[string]int) error {
+	properties := map[string]interface{}{}
+	for key, val := range props {
+		properties[key] = val
+	}
+
+	return m.UpdateUser(ctx, distinctId, &Update{
+		Operation:  "$add",
+		Properties: properties,
+	})
+}
+
+func (m *Mock) IncrementOne(ctx context.Context, distinctId, prop string, delta int) error {
+	return m.Increment(ctx, distinctId, map[string]int{prop: delta})
+}
+
 func (m *Mock) UpdateGroup(ctx context.Context, groupKey, groupUser string, u *Update) error {
 	return nil
 }
 
+func (m *Mock) GroupSet(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error {
+	return nil
+}
+
+func (m *Mock) SetProfile(ctx context.Context, distinctID string, p Profile, extra map[string]interface{}) error {
+	return m.UpdateUser(ctx, distinctID, &Update{
+		Operation:  "$set",
+		Properties: p.Properties(extra),
+	})
+}
+
+func (m *Mock) SetLocation(ctx context.Context, distinctID string, lat, lng float64) error {
+	props, err := LocationProperties(lat, lng)
+	if err != nil {
+		return err
+	}
+
+	return m.UpdateUser(ctx, distinctID, &Update{
+		Operation:  "$set",
+		Properties: props,
+	})
+}
+
+func (m *Mock) TrackCharge(ctx context.Context, distinctID string, amount float64, at time.Time, props map[string]interface{}) error {
+	return m.Append(ctx, distinctID, map[string]interface{}{
+		"$transactions": ChargeProperties(amount, at, props),
+	})
+}
+
+func (m *Mock) GroupSetOnce(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error {
+	return nil
+}
+
+func (m *Mock) GroupUnion(ctx context.Context, groupKey, groupId string, props map[string][]interface{}) error {
+	return nil
+}
+
+func (m *Mock) GroupRemove(ctx context.Context, groupKey, groupId string, props map[string]interface{}) error {
+	return nil
+}
+
+func (m *Mock) GroupUnset(ctx context.Context, groupKey, groupId string, properties []string) error {
+	return nil
+}
+
+func (m *Mock) GroupDelete(ctx context.Context, groupKey, groupId string) error {
+	return nil
+}
+
+func (m *Mock) UpdateGroupBatch(ctx context.Context, groupKey string, updates []*GroupUpdate) error {
+	return nil
+}
+
 func (m *Mock) Alias(ctx context.Context, distinctId, newId string) error {
 	return nil
 }
 
-func (m *Mock) ImportBatch(ctx context.Context, events []*TrackEvent) error {
+func (m *Mock) AliasBatch(ctx context.Context, aliases []AliasPair) error {
+	for _, pair := range aliases {
+		if err := m.Alias(ctx, pair.DistinctID, pair.Alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mock) Merge(ctx context.Context, distinctId1, distinctId2 string) error {
+	return nil
+}
+
+func (m *Mock) ImportBatch(ctx context.Context, events []*ImportEvent) error {
+	for _, event := range events {
+		if err := m.Import(ctx, event.DistinctID, event.EventName, event.Event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mock) ImportBatchAt(ctx context.Context, events []*ImportEvent, at time.Time) error {
+	return m.ImportBatch(ctx, EventsAt(events, at))
+}
+
+func (m *Mock) ImportBatchResult(ctx context.Context, events []*ImportEvent) (*ImportResult, error) {
+	if err := m.ImportBatch(ctx, events); err != nil {
+		return nil, err
+	}
+	return &ImportResult{NumRecordsImported: len(events)}, nil
+}
+
+func (m *Mock) ImportStream(ctx context.Context, r io.Reader) (*ImportResult, error) {
+	stream := newStreamReader(r)
+	result := &ImportResult{}
+	for {
+		event, err := stream.next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		if err := m.Import(ctx, event.DistinctID, event.EventName, event.Event); err != nil {
+			return result, err
+		}
+		result.NumRecordsImported++
+	}
+}
+
+func (m *Mock) Export(ctx context.Context, params ExportParams) (*ExportReader, error) {
+	return nil, errors.New("mixpanel.Mock does not support exports")
+}
+
+func (m *Mock) JQL(ctx context.Context, script string, params map[string]interface{}) ([]json.RawMessage, error) {
+	return nil, errors.New("mixpanel.Mock does not support JQL")
+}
+
+func (m *Mock) Segmentation(ctx context.Context, params SegmentationParams) (*SegmentationResult, error) {
+	return nil, errors.New("mixpanel.Mock does not support segmentation queries")
+}
+
+func (m *Mock) Funnel(ctx context.Context, funnelID int, params FunnelParams) (*FunnelResult, error) {
+	return nil, errors.New("mixpanel.Mock does not support funnel queries")
+}
+
+func (m *Mock) ListFunnels(ctx context.Context) ([]FunnelInfo, error) {
+	return nil, errors.New("mixpanel.Mock does not support funnel queries")
+}
+
+func (m *Mock) Retention(ctx context.Context, params RetentionParams) (*RetentionResult, error) {
+	return nil, errors.New("mixpanel.Mock does not support retention queries")
+}
+
+func (m *Mock) Insights(ctx context.Context, bookmarkID int) (*InsightsResult, error) {
+	return nil, errors.New("mixpanel.Mock does not support insights queries")
+}
+
+func (m *Mock) TopEvents(ctx context.Context, params TopEventsParams) (*TopEventsResult, error) {
+	return nil, errors.New("mixpanel.Mock does not support event queries")
+}
+
+func (m *Mock) EventNames(ctx context.Context, params TopEventsParams) ([]string, error) {
+	return nil, errors.New("mixpanel.Mock does not support event queries")
+}
+
+func (m *Mock) QueryEngage(ctx context.Context, params EngageQuery) (*EngageResults, error) {
+	return nil, errors.New("mixpanel.Mock does not support engage queries")
+}
+
+func (m *Mock) Reconcile(ctx context.Context, distinctIDs []string, expected map[string]map[string]interface{}) ([]Mismatch, error) {
+	return nil, errors.New("mixpanel.Mock does not support engage queries")
+}
+
+func (m *Mock) CreateDeletionTask(ctx context.Context, distinctIDs []string, opts DeletionOpts) (string, error) {
+	return "", errors.New("mixpanel.Mock does not support deletion tasks")
+}
+
+func (m *Mock) GetDeletionStatus(ctx context.Context, taskID string) (*DeletionStatus, error) {
+	return nil, errors.New("mixpanel.Mock does not support deletion tasks")
+}
+
+func (m *Mock) CreateRetrievalTask(ctx context.Context, distinctIDs []string) (string, error) {
+	return "", errors.New("mixpanel.Mock does not support retrieval tasks")
+}
+
+func (m *Mock) GetRetrievalStatus(ctx context.Context, taskID string) (*RetrievalStatus, error) {
+	return nil, errors.New("mixpanel.Mock does not support retrieval tasks")
+}
+
+func (m *Mock) ReplaceLookupTable(ctx context.Context, tableID string, csv io.Reader) error {
+	return errors.New("mixpanel.Mock does not support lookup tables")
+}
+
+func (m *Mock) EncodeTrack(distinctID, eventName string, e *Event) (string, error) {
+	return "", errors.New("mixpanel.Mock does not support encoding")
+}
+
+func (m *Mock) EncodeImport(distinctID, eventName string, e *Event) (string, error) {
+	return "", errors.New("mixpanel.Mock does not support encoding")
+}
+
+func (m *Mock) EncodeUpdate(distinctID string, u *Update) (string, error) {
+	return "", errors.New("mixpanel.Mock does not support encoding")
+}
+
+func (m *Mock) EncodeGroupUpdate(groupKey, groupID string, u *Update) (string, error) {
+	return "", errors.New("mixpanel.Mock does not support encoding")
+}
+
+func (m *Mock) Validate(ctx context.Context) error {
 	return nil
 }
 
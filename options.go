@@ -0,0 +1,491 @@
+package mixpanel
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultCompressionThreshold is the size in bytes from which request bodies
+// are compressed when compression is enabled with WithCompression.
+const DefaultCompressionThreshold = 1024
+
+// Option configures optional behavior of a client created with NewClient.
+type Option func(*mixpanel)
+
+// WithSecret authenticates requests with the project api secret, which is
+// required by the import api.
+func WithSecret(secret string) Option {
+	return func(m *mixpanel) {
+		m.Secret = secret
+	}
+}
+
+// WithServiceAccount authenticates requests to the import, export and query
+// apis with a service account of the project projectID, instead of the api
+// secret. Requests of clients configured with both fail with
+// ErrAmbiguousCredentials. See
+// https://developer.mixpanel.com/reference/service-accounts
+func WithServiceAccount(user, secret string, projectID int) Option {
+	return func(m *mixpanel) {
+		m.ServiceAccountUser = user
+		m.ServiceAccountSecret = secret
+		m.ProjectID = projectID
+	}
+}
+
+// WithComplianceToken authenticates requests to the GDPR apis with the OAuth
+// token found in the privacy settings of the project. See
+// https://developer.mixpanel.com/reference/gdpr-api
+func WithComplianceToken(token string) Option {
+	return func(m *mixpanel) {
+		m.ComplianceToken = token
+	}
+}
+
+// WithBaseURL sends requests to apiURL instead of "https://api.mixpanel.com".
+// A blank url is ignored.
+func WithBaseURL(apiURL string) Option {
+	return func(m *mixpanel) {
+		if apiURL != "" {
+			m.ApiURL = apiURL
+		}
+	}
+}
+
+// WithRegion sends requests to the endpoints of the given data residency
+// region.
+func WithRegion(region Region) Option {
+	return func(m *mixpanel) {
+		m.ApiURL = region.apiURL()
+		m.DataURL = region.dataURL()
+		m.QueryURL = region.queryURL()
+	}
+}
+
+// WithDataURL sends raw data export requests to dataURL instead of
+// "https://data.mixpanel.com". A blank url is ignored.
+func WithDataURL(dataURL string) Option {
+	return func(m *mixpanel) {
+		if dataURL != "" {
+			m.DataURL = dataURL
+		}
+	}
+}
+
+// WithQueryURL sends query api requests to queryURL instead of
+// "https://mixpanel.com". A blank url is ignored.
+func WithQueryURL(queryURL string) Option {
+	return func(m *mixpanel) {
+		if queryURL != "" {
+			m.QueryURL = queryURL
+		}
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request, instead
+// of DefaultUserAgent. A blank user agent sends the default header of
+// net/http.
+func WithUserAgent(ua string) Option {
+	return func(m *mixpanel) {
+		m.UserAgent = ua
+	}
+}
+
+// WithRetry retries requests that failed because of a network error, a 5xx
+// or a 429 response, up to maxAttempts attempts in total. The delay between
+// attempts starts at baseDelay and doubles with every attempt, with some
+// jitter added. Rate limited requests wait for the delay given by the
+// Retry-After header instead, unless that would exceed the context deadline.
+// Without this option, rate limited requests are still retried once.
+// Aliases, merges, and $add and $append updates are not idempotent and are
+// never retried, since a retried increment could be counted twice.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(m *mixpanel) {
+		m.MaxAttempts = maxAttempts
+		m.RetryDelay = baseDelay
+	}
+}
+
+// WithCompression gzips request bodies of at least
+// DefaultCompressionThreshold bytes.
+func WithCompression() Option {
+	return WithCompressionThreshold(DefaultCompressionThreshold)
+}
+
+// WithCompressionThreshold gzips request bodies of at least threshold bytes.
+func WithCompressionThreshold(threshold int) Option {
+	return func(m *mixpanel) {
+		m.Compress = true
+		m.CompressionThreshold = threshold
+	}
+}
+
+// WithHTTPClient sends requests using c, e.g. to go through a proxy or use
+// custom TLS settings, instead of a client with the transport of
+// NewHTTPTransport and a Timeout of DefaultHTTPTimeout, 60 seconds, which
+// also bounds the reading of exports. A nil client is ignored.
+func WithHTTPClient(c *http.Client) Option {
+	return func(m *mixpanel) {
+		if c != nil {
+			m.Client = c
+		}
+	}
+}
+
+// WithMillisecondTime sends event timestamps with millisecond rather than
+// second precision. Events sent with different precisions are ordered by
+// their truncated time, so a project should stick to a single precision.
+func WithMillisecondTime() Option {
+	return func(m *mixpanel) {
+		m.MillisecondTime = true
+	}
+}
+
+// WithVerboseImport makes the import methods return an *ErrImportFailed
+// listing the events mixpanel rejected, along with the reason why. Use
+// ImportBatchResult to also get the number of imported events on success.
+func WithVerboseImport() Option {
+	return func(m *mixpanel) {
+		m.VerboseImport = true
+	}
+}
+
+// WithLogger logs the requests sent by the client, their responses and
+// retries to logger. A nil logger is ignored.
+func WithLogger(logger Logger) Option {
+	return func(m *mixpanel) {
+		if logger != nil {
+			m.Logger = logger
+		}
+	}
+}
+
+// WithTracer traces every request sent by the client with tracer. Tracers
+// added by several options are all called, in order. A nil tracer is
+// ignored. See the mixpanelotel package for OpenTelemetry.
+func WithTracer(tracer Tracer) Option {
+	return func(m *mixpanel) {
+		if tracer == nil {
+			return
+		}
+		if _, ok := m.Tracer.(nopTracer); ok || m.Tracer == nil {
+			m.Tracer = tracer
+		} else {
+			m.Tracer = multiTracer{m.Tracer, tracer}
+		}
+	}
+}
+
+// WithSendCallback calls callback once every request sent by the client
+// completed, after its retries, as a lighter alternative to WithTracer for
+// metrics. The endpoint is an operation such as "track", "import" or
+// "engage", and eventCount the number of events or updates the request
+// carried, which is the size of the chunk for batches split into several
+// requests. The status is zero when no response was received. A nil
+// callback is ignored.
+func WithSendCallback(callback func(endpoint string, eventCount int, status int, err error)) Option {
+	if callback == nil {
+		return func(m *mixpanel) {}
+	}
+
+	return WithTracer(callbackTracer(callback))
+}
+
+// WithClock sets the function returning the current time, instead of
+// time.Now, as when freezing time in tests. It dates the charges tracked
+// without a time, the window of WithDedup, and the Retry-After dates of rate
+// limited requests. Events and updates without a timestamp are still sent
+// without one, for mixpanel to date them on receipt. A nil clock is
+// ignored.
+func WithClock(clock func() time.Time) Option {
+	return func(m *mixpanel) {
+		if clock != nil {
+			m.Clock = clock
+		}
+	}
+}
+
+// WithDedup remembers the $insert_id of the last size events sent with the
+// track api for ttl, and skips events sent again within that window, as
+// when a call is accidentally repeated. Skipped events are logged at debug
+// level and reported as sent. Events without an insert id are always sent,
+// see WithAutoInsertID. Mixpanel deduplicates events by insert id on its
+// side too, including imports, which are not affected by this option.
+func WithDedup(size int, ttl time.Duration) Option {
+	return func(m *mixpanel) {
+		if size < 1 || ttl <= 0 {
+			m.dedup = nil
+			return
+		}
+		m.dedup = newDedupCache(size, ttl)
+	}
+}
+
+// WithHook calls hook after every attempt at sending a request, including
+// retries. Hooks are called in the order they were added. A nil hook is
+// ignored.
+func WithHook(hook RoundTripHook) Option {
+	return func(m *mixpanel) {
+		if hook != nil {
+			m.Hooks = append(m.Hooks, hook)
+		}
+	}
+}
+
+// WithMinImportChunkSize stops splitting import batches rejected as too
+// large once they are no larger than size events. Batches are split down to
+// single events by default. The events which still can't be imported are
+// reported with an *ErrPayloadTooLarge.
+func WithMinImportChunkSize(size int) Option {
+	return func(m *mixpanel) {
+		m.MinImportChunkSize = size
+	}
+}
+
+// BatchLimits are the largest numbers of records sent in a single request
+// by the batch methods. Zero fields keep the limits of the mixpanel apis:
+// MaxTrackBatchSize, MaxImportBatchSize, MaxEngageBatchSize and
+// MaxGroupBatchSize. Mixpanel rejects larger batches, so higher limits are
+// only useful behind a proxy splitting them again.
+type BatchLimits struct {
+	Track  int
+	Import int
+	Engage int
+	Group  int
+}
+
+// WithBatchLimits overrides the number of records sent in a single request
+// by TrackBatch, ImportBatch, ImportStream, AliasBatch, UpdateBatch and
+// UpdateGroupBatch, as when a proxy accepts smaller batches than mixpanel.
+func WithBatchLimits(limits BatchLimits) Option {
+	return func(m *mixpanel) {
+		m.BatchLimits = limits
+	}
+}
+
+// WithConcurrency sends up to n chunks of a batch at the same time, instead
+// of one after the other. Only n chunks are encoded at any time. Errors of
+// an *ErrBatchFailed are still ordered by chunk, whatever order they were
+// sent in. Loggers, tracers and hooks of the client must be safe for
+// concurrent use when n is more than 1.
+func WithConcurrency(n int) Option {
+	return func(m *mixpanel) {
+		m.Concurrency = n
+	}
+}
+
+// WithProjectTimezone reads the wall clock of event and update timestamps in
+// loc, ignoring their own location. This suits times parsed without a zone
+// but meant in the timezone of the project. By default timestamps are sent
+// as the instant they represent, converted to a UTC epoch.
+func WithProjectTimezone(loc *time.Location) Option {
+	return func(m *mixpanel) {
+		m.ProjectTimezone = loc
+	}
+}
+
+// WithSkipMalformedLines makes ImportStream skip lines which are not valid
+// events, or fail validation, instead of stopping the import. Skipped lines
+// are counted in ImportResult.Skipped.
+func WithSkipMalformedLines() Option {
+	return func(m *mixpanel) {
+		m.SkipMalformedLines = true
+	}
+}
+
+// WithFlattenProperties flattens objects nested in the properties of events
+// into top level properties, whose keys join the keys of every level, as in
+// "order.item.sku". Mixpanel drops properties nested too deeply, so this
+// keeps the fields of complex objects. Events with lists of objects are
+// rejected with a *ValidationError unless opts.FlattenArrays is set, and so
+// are events with several properties given the same key once flattened, as
+// "a.b" and {"a": {"b": ...}}. Super and context properties are flattened
+// along with the properties of each event.
+func WithFlattenProperties(opts FlattenOpts) Option {
+	return func(m *mixpanel) {
+		m.Flatten = &opts
+	}
+}
+
+// WithAutoInsertID sets the $insert_id of events which have none, so that
+// mixpanel deduplicates the events sent again by retries. The id is a
+// version 5 UUID of the SHA-1 hash of the event name and of the JSON
+// encoding of all the properties of the event, including distinct_id and
+// time. Events with the same content therefore share an id, but mixpanel
+// only deduplicates events which also share their name, distinct id and
+// time to the second, within the deduplication window of its ingestion.
+// Events without Timestamp get their time from mixpanel, so only their
+// retries within the same second are deduplicated; set Timestamp on events
+// which may be retried later.
+func WithAutoInsertID() Option {
+	return func(m *mixpanel) {
+		m.AutoInsertID = true
+	}
+}
+
+// WithInsertIDFunc sets the $insert_id of events which have none to the id
+// returned by f, such as one derived from the primary key of the record the
+// event is about. f must not modify the event. Events for which f returns
+// an empty id are sent without one, or with the id of WithAutoInsertID.
+func WithInsertIDFunc(f func(distinctID, eventName string, e *Event) string) Option {
+	return func(m *mixpanel) {
+		m.InsertIDFunc = f
+	}
+}
+
+// WithEndpointURLs overrides the URLs of some endpoints, keyed by "track",
+// "import", "engage", "groups", "export", "jql", "segmentation", "funnels",
+// "funnels/list", "retention", "insights", "events/top", "events/names",
+// "engage/query" for QueryEngage and Validate, "lookup-tables",
+// "data-deletions" or "data-retrievals", as when they are proxied to
+// different hosts. URLs are complete, without query string, as in
+// "https://ingest.example.com/mixpanel/track". The id of the table or task
+// is appended to the URLs of "lookup-tables", after a slash, and of
+// "data-deletions" and "data-retrievals", which end with one. Other
+// endpoints keep the URLs set by WithBaseURL, WithDataURL, WithQueryURL or
+// WithRegion.
+func WithEndpointURLs(urls map[string]string) Option {
+	return func(m *mixpanel) {
+		m.EndpointURLs = map[string]string{}
+		for endpoint, url := range urls {
+			m.EndpointURLs[endpoint] = url
+		}
+	}
+}
+
+// Where the data of the track, engage and groups apis is sent
+type Transport int
+
+const (
+	// TransportBody sends the data in the body of requests.
+	TransportBody Transport = iota
+
+	// TransportQuery sends the data of single events and updates in the
+	// query string of requests, as some proxies require. URLs are limited
+	// in length by servers and proxies, which may truncate or reject them,
+	// so requests longer than MaxQueryTransportLength fail without being
+	// sent, unless the client was created with WithQueryFallback. Batches
+	// are always sent in the body, so they never need to be split to fit.
+	TransportQuery
+)
+
+// MaxQueryTransportLength is the longest URL sent with TransportQuery.
+const MaxQueryTransportLength = 8000
+
+// WithTransport sets where the data of the track, engage and groups apis is
+// sent. Defaults to TransportBody, which has no length limit.
+func WithTransport(transport Transport) Option {
+	return func(m *mixpanel) {
+		m.Transport = transport
+	}
+}
+
+// WithQueryFallback sends the requests too long for TransportQuery in the
+// body instead of failing them, logging a warning with the logger of the
+// client, see WarnLogger.
+func WithQueryFallback() Option {
+	return func(m *mixpanel) {
+		m.QueryFallback = true
+	}
+}
+
+// WithRawJSONBody sends the data of the track, engage and groups apis as a
+// JSON list in the body, with a Content-Type of application/json, rather
+// than base64 encoded in a "data=" form. Bodies are about a third smaller,
+// and readable as is. Data sent in the query string with TransportQuery
+// is still base64 encoded.
+func WithRawJSONBody() Option {
+	return func(m *mixpanel) {
+		m.RawJSONBody = true
+	}
+}
+
+// How the time.Time values of properties are sent
+type TimeFormat int
+
+const (
+	// TimeFormatEpoch sends times as the number of seconds since the Unix
+	// epoch, which mixpanel treats as numbers rather than dates.
+	TimeFormatEpoch TimeFormat = iota
+
+	// TimeFormatISO sends times as "2006-01-02T15:04:05" strings in UTC,
+	// which mixpanel recognizes as dates.
+	TimeFormatISO
+)
+
+// WithTimeFormat sets how the time.Time and *time.Time values of event and
+// profile properties are sent, including those nested in objects and
+// lists. Defaults to TimeFormatEpoch. The timestamps of events and updates
+// are not affected.
+func WithTimeFormat(format TimeFormat) Option {
+	return func(m *mixpanel) {
+		m.TimeFormat = format
+	}
+}
+
+// WithNonFiniteAsNull sends the NaN and infinite numbers of event, profile
+// and group properties as null, including those nested in objects and lists
+// and those of super and context properties, instead of rejecting the
+// events and updates holding them with a *ValidationError.
+func WithNonFiniteAsNull() Option {
+	return func(m *mixpanel) {
+		m.NonFiniteAsNull = true
+	}
+}
+
+// WithDryRun captures the requests of the client instead of sending them,
+// and answers them as successful. Requests are still fully encoded, and
+// can be inspected with DryRunRequests. Captured requests are logged with
+// the logger of the client.
+func WithDryRun() Option {
+	return func(m *mixpanel) {
+		m.DryRun = &dryRun{}
+	}
+}
+
+// WithDefaultTimeout bounds requests made with a context without deadline
+// to d, retries included. Exports are bounded until their reader is closed.
+// Contexts with a deadline are left as is.
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(m *mixpanel) {
+		m.DefaultTimeout = d
+	}
+}
+
+// WithStrictValidation rejects events tracked or imported without a token,
+// distinct id or name with a *ValidationError, before sending them. Leave it
+// out to send anonymous events without a distinct id.
+func WithStrictValidation() Option {
+	return func(m *mixpanel) {
+		m.StrictValidation = true
+	}
+}
+
+// WithPropertyValidation rejects events and profile updates setting
+// properties which mixpanel would reject or mangle, with a *ValidationError
+// listing them, before sending them. Invalid properties are those using a
+// reserved "$" or "mp_" name that isn't listed by ReservedEventProperties or
+// ReservedProfileProperties, with a name longer than MaxPropertyNameLength,
+// or nested deeper than MaxPropertyDepth.
+func WithPropertyValidation() Option {
+	return func(m *mixpanel) {
+		m.PropertyValidation = true
+	}
+}
+
+// WithEventRegistry checks the events tracked with TrackTyped against the
+// schemas of registry.
+func WithEventRegistry(registry *EventRegistry) Option {
+	return func(m *mixpanel) {
+		m.Registry = registry
+	}
+}
+
+// WithMaxErrorBodyBytes quotes at most n bytes of response bodies in error
+// messages and logs, instead of DefaultMaxErrorBodyBytes. Zero or less
+// quotes bodies in full. The Body of errors is never truncated.
+func WithMaxErrorBodyBytes(n int) Option {
+	return func(m *mixpanel) {
+		m.MaxErrorBodyBytes = n
+	}
+}
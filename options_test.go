@@ -0,0 +1,437 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	setup()
+	defer teardown()
+
+	transport := &countingTransport{}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithHTTPClient(&http.Client{Transport: transport}))
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	if transport.requests != 1 {
+		t.Errorf("custom client made %d requests, want 1", transport.requests)
+	}
+
+	m := NewClient("e3bc4100330c35722740fb8c6f5abddc", WithHTTPClient(nil)).(*mixpanel)
+	if m.Client != defaultHTTPClient {
+		t.Error("a nil client should leave the default client in place")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	c := &http.Client{}
+
+	m := NewClient("e3bc4100330c35722740fb8c6f5abddc",
+		WithSecret("mysecret"),
+		WithRegion(RegionEU),
+		WithHTTPClient(c),
+		WithUserAgent("my-agent"),
+	).(*mixpanel)
+
+	if m.Token != "e3bc4100330c35722740fb8c6f5abddc" {
+		t.Errorf("Token returned %+v", m.Token)
+	}
+	if m.Secret != "mysecret" {
+		t.Errorf("Secret returned %+v, want %+v", m.Secret, "mysecret")
+	}
+	if m.ApiURL != "https://api-eu.mixpanel.com" {
+		t.Errorf("ApiURL returned %+v, want %+v", m.ApiURL, "https://api-eu.mixpanel.com")
+	}
+	if m.Client != c {
+		t.Error("Client is not the one given with WithHTTPClient")
+	}
+	if m.UserAgent != "my-agent" {
+		t.Errorf("UserAgent returned %+v, want %+v", m.UserAgent, "my-agent")
+	}
+
+	m = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL("http://localhost:8080")).(*mixpanel)
+	if m.ApiURL != "http://localhost:8080" {
+		t.Errorf("ApiURL returned %+v, want %+v", m.ApiURL, "http://localhost:8080")
+	}
+	if m.Client != defaultHTTPClient {
+		t.Error("Client should default to the shared client")
+	}
+
+	m = New("e3bc4100330c35722740fb8c6f5abddc", "").(*mixpanel)
+	if m.ApiURL != "https://api.mixpanel.com" {
+		t.Errorf("ApiURL returned %+v, want %+v", m.ApiURL, "https://api.mixpanel.com")
+	}
+}
+
+func TestWithMillisecondTime(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL), WithMillisecondTime())
+
+	importTime := time.Date(2016, 3, 3, 15, 17, 53, 123000000, time.UTC)
+
+	client.Import(context.TODO(), "13793", "Signed Up", &Event{
+		Timestamp: &importTime,
+	})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"time\":1457018273123,\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.TrackBatch(context.TODO(), []*TrackEvent{
+		{DistinctID: "13793", EventName: "Signed Up", Event: &Event{Timestamp: &importTime}},
+	})
+
+	want = "[" + want + "]"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestTimestampLocation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	pst := time.FixedZone("PST", -8*60*60)
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"time\":1457018273,\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	// The same instant is sent whatever its location.
+	for _, importTime := range []time.Time{
+		time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC),
+		time.Date(2016, 3, 3, 7, 17, 53, 0, pst),
+	} {
+		client.Import(context.TODO(), "13793", "Signed Up", &Event{Timestamp: &importTime})
+
+		if !reflect.DeepEqual(decodeBody(), want) {
+			t.Errorf("Post body returned %+v, want %+v",
+				decodeBody(), want)
+		}
+	}
+
+	// The wall clock is read in the project timezone.
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL), WithProjectTimezone(pst))
+
+	wallClock := time.Date(2016, 3, 3, 7, 17, 53, 0, time.UTC)
+	client.Import(context.TODO(), "13793", "Signed Up", &Event{Timestamp: &wallClock})
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.UpdateUser(context.TODO(), "13793", &Update{
+		Timestamp:  &wallClock,
+		Operation:  "$set",
+		Properties: map[string]interface{}{"Plan": "Premium"},
+	})
+
+	want = "{\"$distinct_id\":\"13793\",\"$set\":{\"Plan\":\"Premium\"},\"$time\":1457018273,\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL))
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	if ua := LastRequest.Header.Get("User-Agent"); ua != DefaultUserAgent {
+		t.Errorf("User-Agent returned %+v, want %+v", ua, DefaultUserAgent)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithUserAgent("my-agent/2.0"))
+	client.UpdateUser(context.TODO(), "13793", &Update{Operation: "$set", Properties: map[string]interface{}{}})
+
+	if ua := LastRequest.Header.Get("User-Agent"); ua != "my-agent/2.0" {
+		t.Errorf("User-Agent returned %+v, want %+v", ua, "my-agent/2.0")
+	}
+}
+
+func TestWithEndpointURLs(t *testing.T) {
+	var paths []string
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithEndpointURLs(map[string]string{
+		"track":  ts.URL + "/ingest/track",
+		"groups": ts.URL + "/ingest/groups",
+	}))
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+	client.UpdateUser(context.TODO(), "13793", &Update{Operation: "$set", Properties: map[string]interface{}{"Plan": "Premium"}})
+	client.GroupSet(context.TODO(), "company", "Acme", map[string]interface{}{"Seats": 5})
+
+	want := []string{"/ingest/track", "/engage", "/ingest/groups"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths returned %v, want %v", paths, want)
+	}
+
+	paths = nil
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithQueryURL(ts.URL),
+		WithServiceAccount("user", "secret", 1), WithComplianceToken("compliance"),
+		WithEndpointURLs(map[string]string{
+			"engage/query":    ts.URL + "/query/engage",
+			"lookup-tables":   ts.URL + "/query/lookup-tables",
+			"data-deletions":  ts.URL + "/query/deletions/",
+			"data-retrievals": ts.URL + "/query/retrievals/",
+		}))
+
+	client.Validate(context.TODO())
+	client.QueryEngage(context.TODO(), EngageQuery{})
+	client.ReplaceLookupTable(context.TODO(), "1234", strings.NewReader("id,name\n"))
+	client.CreateDeletionTask(context.TODO(), []string{"13793"}, DeletionOpts{})
+	client.GetDeletionStatus(context.TODO(), "5678")
+	client.CreateRetrievalTask(context.TODO(), []string{"13793"})
+	client.GetRetrievalStatus(context.TODO(), "5678")
+
+	want = []string{
+		"/query/engage",
+		"/query/engage",
+		"/query/lookup-tables/1234",
+		"/query/deletions/",
+		"/query/deletions/5678",
+		"/query/retrievals/",
+		"/query/retrievals/5678",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths returned %v, want %v", paths, want)
+	}
+}
+
+func TestWithTransport(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithTransport(TransportQuery))
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if got := decodeURL("data=" + LastRequest.URL.Query().Get("data")); got != want {
+		t.Errorf("data returned %+v, want %+v", got, want)
+	}
+	if len(LastPost) != 0 {
+		t.Errorf("Body returned %q, want none", LastPost)
+	}
+
+	client.TrackBatch(context.TODO(), []*TrackEvent{{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}}})
+
+	if !reflect.DeepEqual(decodeBody(), "["+want+"]") {
+		t.Errorf("Batches should be sent in the body: %+v", decodeBody())
+	}
+	if LastRequest.URL.Query().Get("data") != "" {
+		t.Errorf("Batches should not be sent in the query: %+v", LastRequest.URL.RawQuery)
+	}
+
+	LastRequest = nil
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{"Notes": strings.Repeat("x", MaxQueryTransportLength)},
+	})
+	var merr *MixpanelError
+	if !errors.As(err, &merr) || LastRequest != nil {
+		t.Errorf("Requests longer than MaxQueryTransportLength should fail without being sent: %v", err)
+	}
+}
+
+func TestWithQueryFallback(t *testing.T) {
+	setup()
+	defer teardown()
+
+	logger := &recordingLogger{}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithTransport(TransportQuery), WithQueryFallback(), WithLogger(logger))
+
+	notes := strings.Repeat("x", MaxQueryTransportLength)
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{"Notes": notes},
+	})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"Notes\":\"" + notes + "\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Long requests should be sent in the body: %v", err)
+	}
+	if LastRequest.URL.Query().Get("data") != "" {
+		t.Errorf("Long requests should not be sent in the query: %+v", LastRequest.URL.RawQuery)
+	}
+	if len(logger.errors) != 0 || !strings.Contains(strings.Join(logger.debugs, "\n"), "in the body") {
+		t.Errorf("Switching to the body should be logged with Debugf by loggers without Warnf: %v, %v", logger.errors, logger.debugs)
+	}
+
+	warnings := &warningLogger{}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithTransport(TransportQuery), WithQueryFallback(), WithLogger(warnings))
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{"Notes": notes},
+	})
+
+	if len(warnings.warnings) != 1 || len(warnings.errors) != 0 {
+		t.Errorf("Switching to the body should log a warning: %v, %v", warnings.warnings, warnings.errors)
+	}
+}
+
+func TestWithRawJSONBody(t *testing.T) {
+	var contentType string
+	var payload []byte
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		switch contentType {
+		case "application/json":
+			payload = body
+		case "application/x-www-form-urlencoded":
+			form, _ := url.ParseQuery(string(body))
+			payload, _ = base64.StdEncoding.DecodeString(form.Get("data"))
+		default:
+			payload = nil
+		}
+		if !json.Valid(payload) {
+			w.Write([]byte(`{"error": "data, missing or empty", "status": 0}`))
+			return
+		}
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	event := &Event{Properties: map[string]interface{}{"Referred By": "Friend"}}
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"Referred By\":\"Friend\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	for _, test := range []struct {
+		opts        []Option
+		contentType string
+		want        string
+	}{
+		{nil, "application/x-www-form-urlencoded", want},
+		{[]Option{WithRawJSONBody()}, "application/json", "[" + want + "]"},
+	} {
+		client = NewClient("e3bc4100330c35722740fb8c6f5abddc", append([]Option{WithBaseURL(ts.URL)}, test.opts...)...)
+
+		if err := client.Track(context.TODO(), "13793", "Signed Up", event); err != nil {
+			t.Errorf("Track returned an error: %v", err)
+		}
+		if contentType != test.contentType {
+			t.Errorf("Content-Type returned %q, want %q", contentType, test.contentType)
+		}
+		if string(payload) != test.want {
+			t.Errorf("Post body returned %s, want %s", payload, test.want)
+		}
+
+		err := client.TrackBatch(context.TODO(), []*TrackEvent{{DistinctID: "13793", EventName: "Signed Up", Event: event}})
+		if err != nil {
+			t.Errorf("TrackBatch returned an error: %v", err)
+		}
+		if string(payload) != "["+want+"]" {
+			t.Errorf("Post body returned %s, want %s", payload, "["+want+"]")
+		}
+	}
+}
+
+func TestWithBatchLimits(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/import" {
+			w.Write([]byte(`{"code": 200, "num_records_imported": 2, "status": "OK"}`))
+			return
+		}
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL),
+		WithBatchLimits(BatchLimits{Track: 2, Import: 3, Engage: 4}))
+
+	events := []*TrackEvent{}
+	updates := []*ProfileUpdate{}
+	for i := 0; i < 5; i++ {
+		events = append(events, &TrackEvent{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}})
+		updates = append(updates, &ProfileUpdate{DistinctID: "13793", Update: &Update{
+			Operation:  "$set",
+			Properties: map[string]interface{}{"Plan": "Premium"},
+		}})
+	}
+
+	for _, test := range []struct {
+		name string
+		send func() error
+		want int
+	}{
+		{"TrackBatch", func() error { return client.TrackBatch(context.TODO(), events) }, 3},
+		{"ImportBatch", func() error { return client.ImportBatch(context.TODO(), events) }, 2},
+		{"UpdateBatch", func() error { return client.UpdateBatch(context.TODO(), updates) }, 2},
+		{"UpdateGroupBatch", func() error {
+			return client.UpdateGroupBatch(context.TODO(), "company", []*GroupUpdate{{GroupID: "Acme", Update: updates[0].Update}})
+		}, 1},
+	} {
+		requests = 0
+		if err := test.send(); err != nil {
+			t.Errorf("%s returned an error: %v", test.name, err)
+		}
+		if requests != test.want {
+			t.Errorf("%s made %d requests, want %d", test.name, requests, test.want)
+		}
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	setup()
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithClock(clock), WithClock(nil))
+	client.TrackCharge(context.TODO(), "13793", 19.99, time.Time{}, nil)
+
+	want := "{\"$append\":{\"$transactions\":{\"$amount\":19.99,\"$time\":\"2016-03-03T15:17:53\"}},\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v", decodeBody(), want)
+	}
+	teardown()
+
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", now.Add(30*time.Second).Format(http.TimeFormat))
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": "too many requests", "status": 0}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithClock(clock), WithRetry(1, time.Millisecond))
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	var rerr *RateLimitError
+	if !errors.As(err, &rerr) || rerr.RetryAfter != 30*time.Second {
+		t.Errorf("Track returned %v, want a *RateLimitError retrying after %s", err, 30*time.Second)
+	}
+}
@@ -0,0 +1,128 @@
+package mixpanel
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Reserved properties of a user profile. Blank fields are left out. See
+// https://docs.mixpanel.com/docs/data-structure/user-profiles#reserved-user-properties
+type Profile struct {
+	Name      string
+	FirstName string
+	LastName  string
+	Email     string
+	Phone     string
+	Avatar    string
+
+	// Time the user signed up. Left out when zero.
+	Created time.Time
+
+	City        string
+	Region      string
+	CountryCode string
+}
+
+// Properties returns the reserved properties of p along with extra, which
+// doesn't override them.
+func (p *Profile) Properties(extra map[string]interface{}) map[string]interface{} {
+	props := map[string]interface{}{}
+	for key, value := range extra {
+		props[key] = value
+	}
+
+	for key, value := range map[string]string{
+		"$name":         p.Name,
+		"$first_name":   p.FirstName,
+		"$last_name":    p.LastName,
+		"$email":        p.Email,
+		"$phone":        p.Phone,
+		"$avatar":       p.Avatar,
+		"$city":         p.City,
+		"$region":       p.Region,
+		"$country_code": p.CountryCode,
+	} {
+		if value != "" {
+			props[key] = value
+		}
+	}
+	if !p.Created.IsZero() {
+		props["$created"] = p.Created.UTC().Format(isoTimeFormat)
+	}
+
+	return props
+}
+
+// SetProfile sets the reserved properties of p on a user, along with the
+// properties of extra. See
+// https://developer.mixpanel.com/reference/profile-set
+func (m *mixpanel) SetProfile(ctx context.Context, distinctID string, p Profile, extra map[string]interface{}) error {
+	return m.UpdateUser(ctx, distinctID, &Update{
+		Operation:  "$set",
+		Properties: p.Properties(extra),
+	})
+}
+
+// LocationProperties returns the $latitude and $longitude properties placing
+// a user at the given coordinates, in degrees. Coordinates out of range, or
+// NaN, are rejected with a *ValidationError.
+func LocationProperties(lat, lng float64) (map[string]interface{}, error) {
+	if !(lat >= -90 && lat <= 90) {
+		return nil, &ValidationError{Field: "properties", Message: fmt.Sprintf("$latitude %v is not between -90 and 90", lat), Properties: []string{"$latitude"}}
+	}
+	if !(lng >= -180 && lng <= 180) {
+		return nil, &ValidationError{Field: "properties", Message: fmt.Sprintf("$longitude %v is not between -180 and 180", lng), Properties: []string{"$longitude"}}
+	}
+
+	return map[string]interface{}{"$latitude": lat, "$longitude": lng}, nil
+}
+
+// SetLocation sets the location of a user to the given coordinates, in
+// degrees, which mixpanel uses instead of geolocating the user from its ip
+// address. See
+// https://docs.mixpanel.com/docs/tracking-best-practices/geolocation
+func (m *mixpanel) SetLocation(ctx context.Context, distinctID string, lat, lng float64) error {
+	props, err := LocationProperties(lat, lng)
+	if err != nil {
+		return &MixpanelError{URL: m.endpointURL("engage"), Err: err}
+	}
+
+	return m.UpdateUser(ctx, distinctID, &Update{
+		Operation:  "$set",
+		Properties: props,
+	})
+}
+
+// ChargeProperties returns the entry of the $transactions list of a user
+// recording a charge of amount at the given time, along with the properties
+// of extra. The amount and time win over properties of extra with the same
+// names. A zero time records the current time.
+func ChargeProperties(amount float64, at time.Time, extra map[string]interface{}) map[string]interface{} {
+	props := map[string]interface{}{}
+	for key, value := range extra {
+		props[key] = value
+	}
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+	props["$amount"] = amount
+	props["$time"] = at.UTC().Format(isoTimeFormat)
+
+	return props
+}
+
+// TrackCharge appends a charge to the $transactions list of a user, which
+// feeds the revenue reports of mixpanel. Refunds are recorded with a
+// negative amount. See
+// https://developer.mixpanel.com/reference/profile-append-to-list-property
+func (m *mixpanel) TrackCharge(ctx context.Context, distinctID string, amount float64, at time.Time, props map[string]interface{}) error {
+	if at.IsZero() {
+		at = m.Clock()
+	}
+
+	return m.Append(ctx, distinctID, map[string]interface{}{
+		"$transactions": ChargeProperties(amount, at, props),
+	})
+}
@@ -0,0 +1,91 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetProfile(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.SetProfile(context.TODO(), "13793", Profile{
+		Name:    "Bob",
+		Email:   "bob@example.com",
+		Created: time.Date(2013, 4, 1, 13, 20, 0, 0, time.UTC),
+	}, map[string]interface{}{
+		"$name": "Robert",
+		"Plan":  "Premium",
+	})
+
+	want := "{\"$distinct_id\":\"13793\",\"$set\":{\"$created\":\"2013-04-01T13:20:00\",\"$email\":\"bob@example.com\",\"$name\":\"Bob\",\"Plan\":\"Premium\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	want = "/engage"
+	path := LastRequest.URL.Path
+
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("path returned %+v, want %+v",
+			path, want)
+	}
+}
+
+func TestTrackCharge(t *testing.T) {
+	setup()
+	defer teardown()
+
+	at := time.Date(2013, 4, 1, 13, 20, 0, 0, time.UTC)
+	client.TrackCharge(context.TODO(), "13793", 19.99, at, map[string]interface{}{
+		"SKU":     "premium-monthly",
+		"$amount": 1,
+	})
+
+	want := "{\"$append\":{\"$transactions\":{\"$amount\":19.99,\"$time\":\"2013-04-01T13:20:00\",\"SKU\":\"premium-monthly\"}},\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.TrackCharge(context.TODO(), "13793", -5, at.In(time.FixedZone("CEST", 2*60*60)), nil)
+
+	want = "{\"$append\":{\"$transactions\":{\"$amount\":-5,\"$time\":\"2013-04-01T13:20:00\"}},\"$distinct_id\":\"13793\",\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestSetLocation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.SetLocation(context.TODO(), "13793", 48.8566, -2.35)
+
+	want := "{\"$distinct_id\":\"13793\",\"$set\":{\"$latitude\":48.8566,\"$longitude\":-2.35},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	LastRequest = nil
+	var verr *ValidationError
+	for _, location := range [][2]float64{{90.5, 0}, {-91, 0}, {0, 180.1}, {0, -181}, {math.NaN(), 0}} {
+		if err := client.SetLocation(context.TODO(), "13793", location[0], location[1]); !errors.As(err, &verr) {
+			t.Errorf("SetLocation(%v, %v) should fail validation: %v", location[0], location[1], err)
+		}
+	}
+	if LastRequest != nil {
+		t.Errorf("invalid locations should not be sent")
+	}
+}
@@ -0,0 +1,166 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrQueryFailed is returned when mixpanel rejected a request to one of its
+// query or export apis.
+type ErrQueryFailed struct {
+	Message  string
+	Body     []byte
+	HTTPCode int
+}
+
+func (err *ErrQueryFailed) Error() string {
+	return fmt.Sprintf("mixpanel query failed: %s", err.Message)
+}
+
+// query sends a request to one of the query apis, passing values in the url
+// of GET requests and as a form in the body of other ones. The body of the
+// returned response must be closed by the caller. Failed requests are
+// reported as an *ErrQueryFailed wrapped in a *MixpanelError, and in an
+// *AuthError, *BadRequestError or *ServerError depending on their status.
+func (m *mixpanel) query(ctx context.Context, operation, method, endpoint string, values url.Values) (_ *http.Response, err error) {
+	var resp *http.Response
+	wrapErr := func(err error) error {
+		return newMixpanelError(endpoint, resp, err)
+	}
+
+	if !m.hasCredentials() {
+		return nil, wrapErr(errors.New("the query apis require an api secret or a service account, use WithSecret or WithServiceAccount"))
+	}
+	if err := m.checkCredentials(); err != nil {
+		return nil, wrapErr(err)
+	}
+
+	var body string
+	if method == http.MethodGet {
+		if values == nil {
+			values = url.Values{}
+		}
+		m.setProjectID(values)
+		if len(values) > 0 {
+			endpoint += "?" + values.Encode()
+		}
+	} else {
+		body = values.Encode()
+		query := url.Values{}
+		m.setProjectID(query)
+		if len(query) > 0 {
+			endpoint += "?" + query.Encode()
+		}
+	}
+
+	ctx, end := m.Tracer.StartSpan(ctx, operation, endpoint, 0)
+	var status int
+	defer func() { end(status, err) }()
+
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, strings.NewReader(body))
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	if method != http.MethodGet {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	request.Header.Set("Accept", "application/json")
+	m.authorize(request)
+	if m.UserAgent != "" {
+		request.Header.Set("User-Agent", m.UserAgent)
+	}
+
+	m.Logger.Debugf("mixpanel: %s %s", method, endpoint)
+	start := time.Now()
+	resp, err = m.roundTrip(request)
+	m.runHooks(request, resp, nil, err, 1, time.Since(start))
+	if err != nil {
+		m.Logger.Errorf("mixpanel: %s %s failed: %v", method, endpoint, err)
+		return nil, wrapErr(err)
+	}
+	status = resp.StatusCode
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, wrapErr(m.queryFailed(method, endpoint, resp))
+	}
+	m.Logger.Debugf("mixpanel: %s %s returned %d", method, endpoint, resp.StatusCode)
+
+	return resp, nil
+}
+
+// queryFailed reads the error reported in the body of a failed response.
+func (m *mixpanel) queryFailed(method, endpoint string, resp *http.Response) error {
+	data, _ := ioutil.ReadAll(resp.Body)
+	m.Logger.Errorf("mixpanel: %s %s returned %d: %s", method, endpoint, resp.StatusCode, m.truncateBody(data))
+
+	var jsonBody struct {
+		Error string `json:"error"`
+	}
+	if derr := m.responseDecodeError(resp, data, json.Unmarshal(data, &jsonBody)); derr != nil {
+		return derr
+	}
+
+	errMsg := fmt.Sprintf("error=%s; httpCode=%d", jsonBody.Error, resp.StatusCode)
+	return classifyError(resp.StatusCode, &ErrQueryFailed{Message: errMsg, HTTPCode: resp.StatusCode, Body: data})
+}
+
+// queryJSON sends a request to one of the query apis and decodes its JSON
+// response into v.
+func (m *mixpanel) queryJSON(ctx context.Context, operation, method, endpoint string, values url.Values, v interface{}) error {
+	ctx, cancel := m.withDefaultTimeout(ctx)
+	defer cancel()
+
+	resp, err := m.query(ctx, operation, method, endpoint, values)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return newMixpanelError(endpoint, resp, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		if derr := m.responseDecodeError(resp, data, err); derr != nil {
+			return newMixpanelError(endpoint, resp, derr)
+		}
+		return &MixpanelError{URL: endpoint, Err: err}
+	}
+
+	return nil
+}
+
+// JQL runs a JQL script, passing params to it as the global params object,
+// and returns the rows of its result. Rows are returned undecoded so they
+// can be unmarshaled into the types of the caller. Scripts which run over
+// the time limit of mixpanel fail with an *ErrQueryFailed, use the context
+// to give up sooner. Requires a client created with a secret or a
+// service account. See
+// https://developer.mixpanel.com/reference/jql
+func (m *mixpanel) JQL(ctx context.Context, script string, params map[string]interface{}) ([]json.RawMessage, error) {
+	values := url.Values{}
+	values.Set("script", script)
+	if params != nil {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("params", string(encoded))
+	}
+
+	var rows []json.RawMessage
+	if err := m.queryJSON(ctx, "jql", http.MethodPost, m.endpointURL("jql"), values, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,96 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJQL(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		r.ParseForm()
+		w.WriteHeader(200)
+		w.Write([]byte(`[{"key": ["Signed Up"], "value": 12}, {"key": ["Logged In"], "value": 3}]`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	script := "function main() { return Events(params).groupBy(['name'], mixpanel.reducer.count()); }"
+	rows, err := client.JQL(context.TODO(), script, map[string]interface{}{"from_date": "2016-03-01"})
+	if err != nil {
+		t.Fatalf("JQL returned an error: %v", err)
+	}
+
+	want := "/api/2.0/jql"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+
+	if got := LastRequest.PostForm.Get("script"); got != script {
+		t.Errorf("script returned %+v, want %+v", got, script)
+	}
+	if got, want := LastRequest.PostForm.Get("params"), `{"from_date":"2016-03-01"}`; got != want {
+		t.Errorf("params returned %+v, want %+v", got, want)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("JQL returned %d rows, want 2", len(rows))
+	}
+
+	var row struct {
+		Key   []string `json:"key"`
+		Value int      `json:"value"`
+	}
+	if err := json.Unmarshal(rows[0], &row); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(row.Key, []string{"Signed Up"}) || row.Value != 12 {
+		t.Errorf("first row returned %+v", row)
+	}
+}
+
+func TestJQLError(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"error": "Script exceeded the time limit"}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	_, err := client.JQL(context.TODO(), "function main() {}", nil)
+
+	var qerr *ErrQueryFailed
+	if !errors.As(err, &qerr) {
+		t.Fatalf("Error should be a *ErrQueryFailed: %v", err)
+	}
+	if qerr.HTTPCode != 400 || qerr.Message != "error=Script exceeded the time limit; httpCode=400" {
+		t.Errorf("Wrong error: %+v", qerr)
+	}
+}
+
+func TestJQLTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer teardown()
+	defer close(done)
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.JQL(ctx, "function main() {}", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Error should wrap context.DeadlineExceeded: %v", err)
+	}
+}
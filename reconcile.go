@@ -0,0 +1,121 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// maxReconcileIDs is the number of profiles fetched by each engage query of
+// Reconcile.
+const maxReconcileIDs = 1000
+
+// A difference found by Reconcile between a profile and its expected
+// properties
+type Mismatch struct {
+	DistinctID string
+
+	// Name of the property which differs, empty when the whole profile is
+	// missing
+	Property string
+
+	// The expected value, and the value of the profile as decoded from
+	// JSON, nil when missing
+	Expected interface{}
+	Actual   interface{}
+
+	// Whether the property, or the profile, doesn't exist
+	Missing bool
+}
+
+// Reconcile fetches the profiles of distinctIDs and compares them with the
+// properties expected for each of them, returning the differences ordered
+// as distinctIDs then by property name. Only the expected properties are
+// compared, after being encoded as they would be sent, so that 3 and 3.0 or
+// a time and its encoding are equal. Profiles which don't exist are
+// reported with a single Mismatch without property. Requires a client
+// created with a secret or a service account.
+func (m *mixpanel) Reconcile(ctx context.Context, distinctIDs []string, expected map[string]map[string]interface{}) ([]Mismatch, error) {
+	properties := map[string]bool{}
+	for _, props := range expected {
+		for key := range props {
+			properties[key] = true
+		}
+	}
+	query := EngageQuery{}
+	for key := range properties {
+		query.OutputProperties = append(query.OutputProperties, key)
+	}
+	sort.Strings(query.OutputProperties)
+
+	profiles := map[string]map[string]interface{}{}
+	for start := 0; start < len(distinctIDs); start += maxReconcileIDs {
+		end := start + maxReconcileIDs
+		if end > len(distinctIDs) {
+			end = len(distinctIDs)
+		}
+		query.DistinctIDs = distinctIDs[start:end]
+
+		results, err := m.QueryEngage(ctx, query)
+		for {
+			if err != nil {
+				return nil, err
+			}
+			for _, profile := range results.Profiles {
+				profiles[profile.DistinctID] = profile.Properties
+			}
+			if !results.HasNext() {
+				break
+			}
+			results, err = results.Next(ctx)
+		}
+	}
+
+	var mismatches []Mismatch
+	for _, id := range distinctIDs {
+		actual, ok := profiles[id]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{DistinctID: id, Missing: true})
+			continue
+		}
+
+		keys := make([]string, 0, len(expected[id]))
+		for key := range expected[id] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			want, err := m.decodedValue(expected[id][key])
+			if err != nil {
+				return nil, err
+			}
+			got, found := actual[key]
+			if !found || !reflect.DeepEqual(got, want) {
+				mismatches = append(mismatches, Mismatch{
+					DistinctID: id,
+					Property:   key,
+					Expected:   expected[id][key],
+					Actual:     got,
+					Missing:    !found,
+				})
+			}
+		}
+	}
+
+	return mismatches, nil
+}
+
+// decodedValue returns value as mixpanel would return it once sent: encoded
+// as by the client, then decoded from JSON.
+func (m *mixpanel) decodedValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(m.encodeTimes(value))
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	err = json.Unmarshal(data, &decoded)
+	return decoded, err
+}
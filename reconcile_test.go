@@ -0,0 +1,50 @@
+package mixpanel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReconcile(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		r.ParseForm()
+		w.WriteHeader(200)
+		w.Write([]byte(`{"page": 0, "page_size": 1000, "session_id": "1234", "status": "ok", "total": 2, "results": [
+			{"$distinct_id": "13793", "$properties": {"Plan": "Premium", "Seats": 3, "Trial End": 1457018273}},
+			{"$distinct_id": "13794", "$properties": {"Plan": "Free"}}
+		]}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	mismatches, err := client.Reconcile(context.TODO(), []string{"13793", "13794", "13795"}, map[string]map[string]interface{}{
+		"13793": {"Plan": "Premium", "Seats": 3, "Trial End": time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)},
+		"13794": {"Plan": "Premium", "Seats": 1},
+		"13795": {"Plan": "Free"},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile returned an error: %v", err)
+	}
+
+	if got, want := LastRequest.PostForm.Get("distinct_ids"), `["13793","13794","13795"]`; got != want {
+		t.Errorf("distinct_ids returned %+v, want %+v", got, want)
+	}
+	if got, want := LastRequest.PostForm.Get("output_properties"), `["Plan","Seats","Trial End"]`; got != want {
+		t.Errorf("output_properties returned %+v, want %+v", got, want)
+	}
+
+	want := []Mismatch{
+		{DistinctID: "13794", Property: "Plan", Expected: "Premium", Actual: "Free"},
+		{DistinctID: "13794", Property: "Seats", Expected: 1, Missing: true},
+		{DistinctID: "13795", Missing: true},
+	}
+	if !reflect.DeepEqual(mismatches, want) {
+		t.Errorf("Reconcile returned %+v, want %+v", mismatches, want)
+	}
+}
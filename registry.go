@@ -0,0 +1,87 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EventRegistry holds the schemas of the events tracked with TrackTyped, see
+// WithEventRegistry. It is safe for concurrent use.
+type EventRegistry struct {
+	mu      sync.RWMutex
+	schemas map[string]map[string]reflect.Kind
+}
+
+// NewEventRegistry returns an empty registry.
+func NewEventRegistry() *EventRegistry {
+	return &EventRegistry{schemas: map[string]map[string]reflect.Kind{}}
+}
+
+// RegisterEvent sets the schema of the events named name: the kind of each
+// of their required properties. Properties declared as reflect.Interface
+// may hold any value. Events may carry properties left out of their schema.
+func (r *EventRegistry) RegisterEvent(name string, schema map[string]reflect.Kind) {
+	copied := make(map[string]reflect.Kind, len(schema))
+	for key, kind := range schema {
+		copied[key] = kind
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.schemas[name] = copied
+}
+
+// Validate checks props against the schema of the events named name. It
+// returns a *ValidationError listing the properties that are missing or of
+// the wrong kind, or naming an event that wasn't registered.
+func (r *EventRegistry) Validate(name string, props map[string]interface{}) error {
+	r.mu.RLock()
+	schema, ok := r.schemas[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return &ValidationError{Field: "event", Message: fmt.Sprintf("%q is not registered", name)}
+	}
+
+	var invalid []string
+	for key, kind := range schema {
+		value, ok := props[key]
+		if !ok {
+			invalid = append(invalid, key)
+			continue
+		}
+		if kind != reflect.Interface && reflect.ValueOf(value).Kind() != kind {
+			invalid = append(invalid, key)
+		}
+	}
+
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	sort.Strings(invalid)
+	return &ValidationError{
+		Field:      "properties",
+		Message:    fmt.Sprintf("missing or of the wrong kind for %q: %s", name, strings.Join(invalid, ", ")),
+		Properties: invalid,
+	}
+}
+
+// TrackTyped tracks an event like Track, once its properties were validated
+// against the schema registered for it in the registry of the client.
+func (m *mixpanel) TrackTyped(ctx context.Context, distinctID, eventName string, e *Event) error {
+	if m.Registry == nil {
+		return &MixpanelError{URL: m.endpointURL("track"), Err: errors.New("TrackTyped requires an event registry, use WithEventRegistry")}
+	}
+	if err := m.Registry.Validate(eventName, e.Properties); err != nil {
+		return &MixpanelError{URL: m.endpointURL("track"), Err: err}
+	}
+
+	return m.Track(ctx, distinctID, eventName, e)
+}
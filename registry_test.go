@@ -0,0 +1,50 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTrackTyped(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registry := NewEventRegistry()
+	registry.RegisterEvent("Signed Up", map[string]reflect.Kind{
+		"plan":     reflect.String,
+		"seats":    reflect.Int,
+		"referrer": reflect.Interface,
+	})
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithEventRegistry(registry))
+
+	client.TrackTyped(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{"plan": "pro", "seats": 3, "referrer": nil, "extra": true},
+	})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"extra\":true,\"plan\":\"pro\",\"referrer\":null,\"seats\":3,\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	err := client.TrackTyped(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{"plan": 1},
+	})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"plan", "referrer", "seats"}) {
+		t.Errorf("TrackTyped should report the invalid properties: %v", err)
+	}
+
+	err = client.TrackTyped(context.TODO(), "13793", "Logged In", &Event{})
+	if !errors.As(err, &verr) || verr.Field != "event" {
+		t.Errorf("TrackTyped should reject unregistered events: %v", err)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL))
+	if err := client.TrackTyped(context.TODO(), "13793", "Signed Up", &Event{}); err == nil {
+		t.Error("TrackTyped without a registry should return an error")
+	}
+}
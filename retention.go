@@ -0,0 +1,105 @@
+package mixpanel
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Parameters of a retention query
+type RetentionParams struct {
+	// First and last day of the cohorts, inclusive. Only the dates are
+	// used, in the timezone of the project.
+	FromDate time.Time
+	ToDate   time.Time
+
+	// "birth" to count the users coming back in each period after doing
+	// BornEvent first, or "compounded" to count the users doing Event again.
+	// Leave empty for "birth".
+	RetentionType string
+
+	// Event putting users in a cohort, required with "birth" retention, and
+	// an expression filtering it
+	BornEvent string
+	BornWhere string
+
+	// Event counted as users coming back, and an expression filtering it.
+	// Leave empty to count any event.
+	Event string
+	Where string
+
+	// Length of the periods, either a number of days in Interval or a Unit
+	// of "day", "week" or "month", and the number of periods counted. Leave
+	// zero for daily periods and the default count of mixpanel.
+	Interval      int
+	Unit          string
+	IntervalCount int
+}
+
+// The counts of a retention query
+type RetentionResult struct {
+	// The cohorts of the query, ordered by date
+	Cohorts []RetentionCohort
+}
+
+// The users of a cohort, and how many came back in each period
+type RetentionCohort struct {
+	// Date the cohort starts, such as "2016-03-01"
+	Date string
+
+	// Users in the cohort
+	First int
+
+	// Users coming back in each period after the one they joined the
+	// cohort
+	Counts []int
+}
+
+// Retention counts the users coming back after joining a cohort, for every
+// period after the cohort starts. Requires a client created with a secret
+// or a service account. See
+// https://developer.mixpanel.com/reference/retention-query
+func (m *mixpanel) Retention(ctx context.Context, params RetentionParams) (*RetentionResult, error) {
+	values := url.Values{}
+	values.Set("from_date", params.FromDate.Format("2006-01-02"))
+	values.Set("to_date", params.ToDate.Format("2006-01-02"))
+	for key, value := range map[string]string{
+		"retention_type": params.RetentionType,
+		"born_event":     params.BornEvent,
+		"born_where":     params.BornWhere,
+		"event":          params.Event,
+		"where":          params.Where,
+		"unit":           params.Unit,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	if params.Interval > 0 {
+		values.Set("interval", strconv.Itoa(params.Interval))
+	}
+	if params.IntervalCount > 0 {
+		values.Set("interval_count", strconv.Itoa(params.IntervalCount))
+	}
+
+	var jsonBody map[string]struct {
+		First  int   `json:"first"`
+		Counts []int `json:"counts"`
+	}
+	if err := m.queryJSON(ctx, "retention", http.MethodGet, m.endpointURL("retention"), values, &jsonBody); err != nil {
+		return nil, err
+	}
+
+	result := &RetentionResult{Cohorts: make([]RetentionCohort, 0, len(jsonBody))}
+	for date, cohort := range jsonBody {
+		result.Cohorts = append(result.Cohorts, RetentionCohort{Date: date, First: cohort.First, Counts: cohort.Counts})
+	}
+	sort.Slice(result.Cohorts, func(i, j int) bool {
+		return result.Cohorts[i].Date < result.Cohorts[j].Date
+	})
+
+	return result, nil
+}
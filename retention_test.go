@@ -0,0 +1,67 @@
+package mixpanel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetention(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`{"2016-03-02": {"counts": [4], "first": 8}, "2016-03-01": {"counts": [6, 3], "first": 10}}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	result, err := client.Retention(context.TODO(), RetentionParams{
+		FromDate:      time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC),
+		ToDate:        time.Date(2016, 3, 2, 0, 0, 0, 0, time.UTC),
+		RetentionType: "birth",
+		BornEvent:     "Signed Up",
+		Event:         "Logged In",
+		Unit:          "day",
+		IntervalCount: 2,
+	})
+	if err != nil {
+		t.Fatalf("Retention returned an error: %v", err)
+	}
+
+	want := "/api/2.0/retention"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+
+	query := LastRequest.URL.Query()
+	for key, want := range map[string]string{
+		"from_date":      "2016-03-01",
+		"to_date":        "2016-03-02",
+		"retention_type": "birth",
+		"born_event":     "Signed Up",
+		"event":          "Logged In",
+		"unit":           "day",
+		"interval_count": "2",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s returned %+v, want %+v", key, got, want)
+		}
+	}
+	for _, key := range []string{"born_where", "where", "interval"} {
+		if _, ok := query[key]; ok {
+			t.Errorf("%s should not be set: %+v", key, query.Get(key))
+		}
+	}
+
+	wantResult := &RetentionResult{Cohorts: []RetentionCohort{
+		{Date: "2016-03-01", First: 10, Counts: []int{6, 3}},
+		{Date: "2016-03-02", First: 8, Counts: []int{4}},
+	}}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("Retention returned %+v, want %+v", result, wantResult)
+	}
+}
@@ -0,0 +1,126 @@
+package mixpanel
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// shouldRetry reports whether a request that returned resp and err may
+// succeed when sent again.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryAfter returns the delay requested by the Retry-After header of resp,
+// which holds either a number of seconds or an HTTP date, compared to now.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	delay := date.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
+
+// DefaultRateLimitDelay is the delay before retrying a rate limited request
+// when mixpanel didn't send a Retry-After header and no retry delay was
+// configured with WithRetry.
+const DefaultRateLimitDelay = time.Second
+
+// MaxRetryDelay caps the delay between two attempts of a request retried
+// with WithRetry.
+const MaxRetryDelay = 30 * time.Second
+
+// backoff returns the delay to wait for after the given failed attempt.
+func (m *mixpanel) backoff(attempt int) time.Duration {
+	delay := m.RetryDelay
+	if delay <= 0 {
+		return 0
+	}
+	// Double the delay once per attempt, stopping at the cap rather than
+	// shifting, which would overflow after a few dozen attempts.
+	for i := 1; i < attempt && delay < MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > MaxRetryDelay {
+		delay = MaxRetryDelay
+	}
+
+	// Wait between half and all of the exponential delay so that clients
+	// that failed at the same time don't retry in lockstep.
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// isIdempotent reports whether params, a single event or update or a batch
+// of them, can safely be sent more than once. Updates adding to numbers or
+// appending to lists would be applied again, unlike the other operations.
+func isIdempotent(params interface{}) bool {
+	switch params := params.(type) {
+	case map[string]interface{}:
+		if _, ok := params["$add"]; ok {
+			return false
+		}
+		if _, ok := params["$append"]; ok {
+			return false
+		}
+		name := params["event"]
+		return name != "$create_alias" && name != "$merge"
+	case []map[string]interface{}:
+		for _, event := range params {
+			if !isIdempotent(event) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// withDefaultTimeout bounds ctx by the default timeout of the client when it
+// has no deadline of its own. The returned function must be called once the
+// request completed.
+func (m *mixpanel) withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.DefaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, m.DefaultTimeout)
+}
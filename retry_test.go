@@ -0,0 +1,287 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			w.WriteHeader(500)
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithRetry(3, time.Millisecond))
+
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{}); err != nil {
+		t.Errorf("Track returned an error: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("Track made %d requests, want 3", requests)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(503)
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithRetry(3, time.Millisecond))
+
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{}); err == nil {
+		t.Error("Track should return an error")
+	}
+	if requests != 3 {
+		t.Errorf("Track made %d requests, want 3", requests)
+	}
+
+	requests = 0
+	client.Alias(context.TODO(), "13793", "new-id")
+	if requests != 1 {
+		t.Errorf("Alias made %d requests, want 1", requests)
+	}
+
+	requests = 0
+	client.IncrementOne(context.TODO(), "13793", "Logins", 1)
+	if requests != 1 {
+		t.Errorf("IncrementOne made %d requests, want 1", requests)
+	}
+
+	requests = 0
+	client.SetOnce(context.TODO(), "13793", map[string]interface{}{"Plan": "Premium"})
+	if requests != 3 {
+		t.Errorf("SetOnce made %d requests, want 3", requests)
+	}
+}
+
+func TestRetrySkipsClientErrors(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(400)
+		w.Write([]byte(`{"error": "bad request", "status": 0}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithRetry(3, time.Millisecond))
+
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{}); err == nil {
+		t.Error("Track should return an error")
+	}
+	if requests != 1 {
+		t.Errorf("Track made %d requests, want 1", requests)
+	}
+}
+
+func TestRetryHonorsContext(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(500)
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithRetry(5, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := client.Track(ctx, "13793", "Signed Up", &Event{}); err == nil {
+		t.Error("Track should return an error")
+	}
+	if requests != 1 {
+		t.Errorf("Track made %d requests, want 1", requests)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(429)
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithRetry(2, time.Hour))
+
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{}); err != nil {
+		t.Errorf("Track returned an error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Track made %d requests, want 2", requests)
+	}
+}
+
+func TestRateLimitError(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(429)
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithRetry(2, time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := client.Track(ctx, "13793", "Signed Up", &Event{})
+
+	var rerr *RateLimitError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Error should be a *RateLimitError: %v", err)
+	}
+	if rerr.RetryAfter != 120*time.Second {
+		t.Errorf("RetryAfter returned %+v, want %+v", rerr.RetryAfter, 120*time.Second)
+	}
+	if requests != 1 {
+		t.Errorf("Track made %d requests, want 1", requests)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+
+	tests := map[string]time.Duration{
+		"":       0,
+		"30":     30 * time.Second,
+		"-1":     0,
+		"-":      0,
+		date:     time.Minute,
+		"junk!!": 0,
+	}
+
+	for header, want := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", header)
+
+		got, _ := retryAfter(resp, time.Now())
+		if got > want || got < want-2*time.Second {
+			t.Errorf("retryAfter(%q) returned %+v, want %+v", header, got, want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	m := NewClient("e3bc4100330c35722740fb8c6f5abddc", WithRetry(100, time.Second)).(*mixpanel)
+
+	for attempt := 1; attempt < 100; attempt++ {
+		delay := m.backoff(attempt)
+		if delay <= 0 || delay > MaxRetryDelay {
+			t.Errorf("backoff(%d) returned %+v, want a delay in (0, %s]", attempt, delay, MaxRetryDelay)
+		}
+	}
+
+	if delay := m.backoff(1); delay > time.Second {
+		t.Errorf("backoff(1) returned %+v, want at most %s", delay, time.Second)
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	tests := []struct {
+		params interface{}
+		want   bool
+	}{
+		{map[string]interface{}{"event": "Signed Up"}, true},
+		{map[string]interface{}{"event": "$merge"}, false},
+		{map[string]interface{}{"event": "$create_alias"}, false},
+		{map[string]interface{}{"$token": "token", "$set": map[string]interface{}{}}, true},
+		{map[string]interface{}{"$token": "token", "$union": map[string]interface{}{}}, true},
+		{map[string]interface{}{"$token": "token", "$add": map[string]interface{}{}}, false},
+		{map[string]interface{}{"$token": "token", "$append": map[string]interface{}{}}, false},
+		{[]map[string]interface{}{{"$set": map[string]interface{}{}}, {"$add": map[string]interface{}{}}}, false},
+		{[]map[string]interface{}{{"event": "Signed Up"}, {"event": "Logged In"}}, true},
+		{[]map[string]interface{}{{"event": "Signed Up"}, {"event": "$merge"}}, false},
+	}
+
+	for _, test := range tests {
+		if got := isIdempotent(test.params); got != test.want {
+			t.Errorf("isIdempotent(%v) returned %v, want %v", test.params, got, test.want)
+		}
+	}
+}
+
+func TestRetryAfterByDefault(t *testing.T) {
+	requests := 0
+	status := 429
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 || status == 429 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(429)
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"error": null, "status": 1}`))
+	}))
+	defer teardown()
+
+	client = New("e3bc4100330c35722740fb8c6f5abddc", ts.URL)
+
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	var rerr *RateLimitError
+	if !errors.As(err, &rerr) {
+		t.Errorf("Error should be a *RateLimitError: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Track made %d requests, want 2", requests)
+	}
+
+	requests = 0
+	status = 200
+
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{}); err != nil {
+		t.Errorf("Track returned an error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Track made %d requests, want 2", requests)
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer teardown()
+	defer close(done)
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithDefaultTimeout(20*time.Millisecond))
+
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Error should wrap context.DeadlineExceeded: %v", err)
+	}
+
+	// A deadline of the caller takes precedence over the default timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	client.Track(ctx, "13793", "Signed Up", &Event{})
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("request gave up after %s, before the deadline of its context", elapsed)
+	}
+}
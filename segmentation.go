@@ -0,0 +1,99 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Parameters of a segmentation query, counting the occurrences of an event
+// over time
+type SegmentationParams struct {
+	// Name of the counted event. Required.
+	Event string
+
+	// First and last day of the query, inclusive. Only the dates are used,
+	// in the timezone of the project.
+	FromDate time.Time
+	ToDate   time.Time
+
+	// Segment the events by the value of this expression, such as
+	// `properties["Plan"]`. Leave empty to count all events together. See
+	// https://developer.mixpanel.com/reference/segmentation-expressions
+	On string
+
+	// Only count events matching this expression
+	Where string
+
+	// Size of the time buckets: "minute", "hour", "day", "week" or
+	// "month". Leave empty for "day".
+	Unit string
+
+	// What is counted: "general" for all events, "unique" for the users
+	// sending them, or "average" for the average number of events per user.
+	// Leave empty for "general".
+	Type string
+
+	// Maximum number of segments returned, mixpanel returns 60 when left
+	// zero and at most 10000. Segments with the most events are returned
+	// first.
+	Limit int
+}
+
+// The counts of a segmentation query
+type SegmentationResult struct {
+	// The time buckets of the query, in order, formatted as by mixpanel
+	// such as "2016-03-01" for days
+	Series []string
+
+	// The count of every time bucket, keyed by segment then bucket. Events
+	// of segmentation queries without On are counted under the name of the
+	// event.
+	Values map[string]map[string]float64
+}
+
+// Segmentation counts the occurrences of an event over time, split by
+// segment. All the segments, up to the limit, are returned at once.
+// Requires a client created with a secret or a service account. See
+// https://developer.mixpanel.com/reference/segmentation-query
+func (m *mixpanel) Segmentation(ctx context.Context, params SegmentationParams) (*SegmentationResult, error) {
+	endpoint := m.endpointURL("segmentation")
+	if params.Event == "" {
+		return nil, &MixpanelError{URL: endpoint, Err: errors.New("segmentation requires an event")}
+	}
+
+	values := url.Values{}
+	values.Set("event", params.Event)
+	values.Set("from_date", params.FromDate.Format("2006-01-02"))
+	values.Set("to_date", params.ToDate.Format("2006-01-02"))
+	if params.On != "" {
+		values.Set("on", params.On)
+	}
+	if params.Where != "" {
+		values.Set("where", params.Where)
+	}
+	if params.Unit != "" {
+		values.Set("unit", params.Unit)
+	}
+	if params.Type != "" {
+		values.Set("type", params.Type)
+	}
+	if params.Limit > 0 {
+		values.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	var jsonBody struct {
+		Data struct {
+			Series []string                      `json:"series"`
+			Values map[string]map[string]float64 `json:"values"`
+		} `json:"data"`
+	}
+	if err := m.queryJSON(ctx, "segmentation", http.MethodGet, endpoint, values, &jsonBody); err != nil {
+		return nil, err
+	}
+
+	return &SegmentationResult{Series: jsonBody.Data.Series, Values: jsonBody.Data.Values}, nil
+}
@@ -0,0 +1,111 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSegmentation(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`{"data": {"series": ["2016-03-01", "2016-03-02"], "values": {"Premium": {"2016-03-01": 3, "2016-03-02": 5}}}, "legend_size": 1}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	result, err := client.Segmentation(context.TODO(), SegmentationParams{
+		Event:    "Signed Up",
+		FromDate: time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC),
+		ToDate:   time.Date(2016, 3, 2, 0, 0, 0, 0, time.UTC),
+		On:       `properties["Plan"]`,
+		Where:    `properties["Country"] == "France"`,
+		Unit:     "day",
+		Type:     "unique",
+		Limit:    10,
+	})
+	if err != nil {
+		t.Fatalf("Segmentation returned an error: %v", err)
+	}
+
+	want := "/api/2.0/segmentation"
+	if path := LastRequest.URL.Path; path != want {
+		t.Errorf("path returned %+v, want %+v", path, want)
+	}
+	if user, _, _ := LastRequest.BasicAuth(); user != "mysecret" {
+		t.Errorf("basic auth user returned %+v, want mysecret", user)
+	}
+
+	query := LastRequest.URL.Query()
+	for key, want := range map[string]string{
+		"event":     "Signed Up",
+		"from_date": "2016-03-01",
+		"to_date":   "2016-03-02",
+		"on":        `properties["Plan"]`,
+		"where":     `properties["Country"] == "France"`,
+		"unit":      "day",
+		"type":      "unique",
+		"limit":     "10",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s returned %+v, want %+v", key, got, want)
+		}
+	}
+
+	wantResult := &SegmentationResult{
+		Series: []string{"2016-03-01", "2016-03-02"},
+		Values: map[string]map[string]float64{
+			"Premium": {"2016-03-01": 3, "2016-03-02": 5},
+		},
+	}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("Segmentation returned %+v, want %+v", result, wantResult)
+	}
+}
+
+func TestSegmentationDefaults(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LastRequest = r
+		w.WriteHeader(200)
+		w.Write([]byte(`{"data": {"series": ["2016-03-01"], "values": {"Signed Up": {"2016-03-01": 12}}}, "legend_size": 1}`))
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	day := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := client.Segmentation(context.TODO(), SegmentationParams{Event: "Signed Up", FromDate: day, ToDate: day}); err != nil {
+		t.Fatalf("Segmentation returned an error: %v", err)
+	}
+
+	query := LastRequest.URL.Query()
+	for _, key := range []string{"on", "where", "unit", "type", "limit"} {
+		if _, ok := query[key]; ok {
+			t.Errorf("%s should not be set: %+v", key, query.Get(key))
+		}
+	}
+}
+
+func TestSegmentationRequiresEvent(t *testing.T) {
+	setup()
+	defer teardown()
+	LastRequest = nil
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithQueryURL(ts.URL))
+
+	_, err := client.Segmentation(context.TODO(), SegmentationParams{})
+
+	var merr *MixpanelError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Error should be a *MixpanelError: %v", err)
+	}
+	if LastRequest != nil {
+		t.Errorf("Segmentation without event should not send a request")
+	}
+}
@@ -0,0 +1,100 @@
+package mixpanel
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// maxSmallEventProperties is the largest number of properties, including
+// token and distinct_id, of the events encoded by encodeSmallEvent.
+const maxSmallEventProperties = 8
+
+// encodeSmallEvent writes the JSON encoding of params to buf, as
+// json.Marshal would, if params are the track parameters of a single event
+// with at most maxSmallEventProperties properties which are strings needing
+// no escaping, booleans or integers. It returns false without writing
+// anything otherwise. This avoids the reflection and the sorting of
+// encoding/json for the common events with a few properties.
+func encodeSmallEvent(buf *bytes.Buffer, params interface{}) bool {
+	event, ok := params.(map[string]interface{})
+	if !ok || len(event) != 2 {
+		return false
+	}
+	name, ok := event["event"].(string)
+	if !ok || !isPlainJSONString(name) {
+		return false
+	}
+	props, ok := event["properties"].(map[string]interface{})
+	if !ok || len(props) > maxSmallEventProperties {
+		return false
+	}
+
+	var array [maxSmallEventProperties]string
+	keys := array[:0]
+	for key, value := range props {
+		if !isPlainJSONString(key) || !isSmallEventValue(value) {
+			return false
+		}
+		keys = append(keys, key)
+	}
+	// Insertion sort, which doesn't allocate and is quick for a few keys.
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+
+	var scratch [20]byte
+	buf.WriteString(`{"event":"`)
+	buf.WriteString(name)
+	buf.WriteString(`","properties":{`)
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('"')
+		buf.WriteString(key)
+		buf.WriteString(`":`)
+
+		switch value := props[key].(type) {
+		case string:
+			buf.WriteByte('"')
+			buf.WriteString(value)
+			buf.WriteByte('"')
+		case bool:
+			buf.Write(strconv.AppendBool(scratch[:0], value))
+		case int:
+			buf.Write(strconv.AppendInt(scratch[:0], int64(value), 10))
+		case int64:
+			buf.Write(strconv.AppendInt(scratch[:0], value, 10))
+		}
+	}
+	buf.WriteString("}}")
+
+	return true
+}
+
+func isSmallEventValue(value interface{}) bool {
+	switch value := value.(type) {
+	case string:
+		return isPlainJSONString(value)
+	case bool, int, int64:
+		return true
+	}
+	return false
+}
+
+// isPlainJSONString reports whether s is encoded by encoding/json as is
+// between quotes: it only has printable ASCII characters, none of which
+// needs escaping, including the <, > and & escaped for HTML.
+func isPlainJSONString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c < 0x20 || c > 0x7e:
+			return false
+		case c == '"' || c == '\\' || c == '<' || c == '>' || c == '&':
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package mixpanel
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestEncodeSmallEvent(t *testing.T) {
+	for _, test := range []struct {
+		params interface{}
+		small  bool
+	}{
+		{map[string]interface{}{"event": "Signed Up", "properties": map[string]interface{}{"token": "abc", "distinct_id": "13793", "time": int64(1457018273), "Seats": 3, "Trial": true}}, true},
+		{map[string]interface{}{"event": "Signed Up", "properties": map[string]interface{}{}}, true},
+		{map[string]interface{}{"event": "Signed Up", "properties": map[string]interface{}{"Plan": "<b>Premium</b>"}}, false},
+		{map[string]interface{}{"event": "Signed Up", "properties": map[string]interface{}{"Plan": "Prémium"}}, false},
+		{map[string]interface{}{"event": "Signed Up", "properties": map[string]interface{}{"Ratio": 0.5}}, false},
+		{map[string]interface{}{"event": "Signed\nUp", "properties": map[string]interface{}{}}, false},
+		{map[string]interface{}{"event": "Signed Up", "properties": map[string]interface{}{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8, "i": 9}}, false},
+		{[]map[string]interface{}{{"event": "Signed Up", "properties": map[string]interface{}{}}}, false},
+	} {
+		var buf bytes.Buffer
+		small := encodeSmallEvent(&buf, test.params)
+		if small != test.small {
+			t.Errorf("encodeSmallEvent(%+v) returned %v, want %v", test.params, small, test.small)
+		}
+		if !small {
+			if buf.Len() != 0 {
+				t.Errorf("encodeSmallEvent(%+v) wrote %s without encoding the event", test.params, buf.String())
+			}
+			continue
+		}
+
+		want, _ := json.Marshal(test.params)
+		if buf.String() != string(want) {
+			t.Errorf("encodeSmallEvent returned %s, want %s", buf.String(), want)
+		}
+	}
+}
+
+// FuzzEncodeSmallEvent checks that encodeSmallEvent encodes events exactly
+// as encoding/json does.
+func FuzzEncodeSmallEvent(f *testing.F) {
+	f.Add("Signed Up", "Plan", "Premium", "Seats", int64(3), true)
+	f.Add("", "", "", "a", int64(-1), false)
+	f.Add("<script>", "a\"b", " ", "c\\d", int64(1)<<62, true)
+
+	f.Fuzz(func(t *testing.T, name, key1, value1, key2 string, value2 int64, value3 bool) {
+		params := map[string]interface{}{
+			"event": name,
+			"properties": map[string]interface{}{
+				"token": "e3bc4100330c35722740fb8c6f5abddc",
+				key1:    value1,
+				key2:    value2,
+				"Trial": value3,
+			},
+		}
+
+		var buf bytes.Buffer
+		if !encodeSmallEvent(&buf, params) {
+			return
+		}
+
+		want, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != string(want) {
+			t.Errorf("encodeSmallEvent returned %s, want %s", buf.String(), want)
+		}
+	})
+}
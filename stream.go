@@ -0,0 +1,192 @@
+package mixpanel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrMalformedLine is returned by ImportStream for a line which is not a
+// valid event, unless the client was created with WithSkipMalformedLines.
+type ErrMalformedLine struct {
+	// Number of the line in the stream, starting at 1
+	Line int
+
+	Err error
+}
+
+func (err *ErrMalformedLine) Error() string {
+	return fmt.Sprintf("mixpanel: malformed event on line %d: %v", err.Line, err.Err)
+}
+
+func (err *ErrMalformedLine) Unwrap() error {
+	return err.Err
+}
+
+// streamReader reads the events of a newline-delimited JSON stream.
+type streamReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newStreamReader(r io.Reader) *streamReader {
+	return &streamReader{r: bufio.NewReader(r)}
+}
+
+// next returns the event of the next non-blank line, an *ErrMalformedLine
+// if it is not a valid event, or io.EOF at the end of the stream.
+func (s *streamReader) next() (*ImportEvent, error) {
+	for {
+		data, err := s.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(data) == 0 && err == io.EOF {
+			return nil, io.EOF
+		}
+		s.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		var line struct {
+			Event      string                 `json:"event"`
+			Properties map[string]interface{} `json:"properties"`
+		}
+		// Numbers are kept as json.Number so that integers above 2^53, such
+		// as ids, are sent as they were read.
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&line); err != nil {
+			return nil, &ErrMalformedLine{Line: s.line, Err: err}
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return nil, &ErrMalformedLine{Line: s.line, Err: errors.New("line holds more than an event")}
+		}
+		if line.Event == "" {
+			return nil, &ErrMalformedLine{Line: s.line, Err: errors.New("event is empty")}
+		}
+		if line.Properties == nil {
+			return nil, &ErrMalformedLine{Line: s.line, Err: errors.New("properties are missing")}
+		}
+
+		distinctID, _ := line.Properties["distinct_id"].(string)
+		return &ImportEvent{
+			DistinctID: distinctID,
+			EventName:  line.Event,
+			Event:      &Event{Properties: line.Properties},
+		}, nil
+	}
+}
+
+// ImportStream imports the events of a stream of newline-delimited JSON,
+// with one {"event": ..., "properties": {...}} object per line, in batches
+// of MaxImportBatchSize events, or as set by WithBatchLimits. Only a single
+// batch is held in memory at a time. Malformed lines stop the import with an
+// *ErrMalformedLine, or are counted in Skipped when the client was created
+// with WithSkipMalformedLines. Failing batches are reported as described for
+// ImportBatch. The indexes of the rejected events count the events of the
+// stream, not its lines.
+func (m *mixpanel) ImportStream(ctx context.Context, r io.Reader) (*ImportResult, error) {
+	total := &ImportResult{}
+	stream := newStreamReader(r)
+
+	var (
+		mu      sync.Mutex
+		errs    []error
+		offsets []int
+		batch   []*ImportEvent
+		params  []map[string]interface{}
+		offset  int
+	)
+	fail := func(offset int, err error) {
+		var berr *ErrBatchFailed
+		if errors.As(err, &berr) {
+			for range berr.Errors {
+				offsets = append(offsets, offset)
+			}
+			errs = append(errs, berr.Errors...)
+		} else {
+			offsets = append(offsets, offset)
+			errs = append(errs, err)
+		}
+	}
+	flush := func() error {
+		result := &ImportResult{}
+		err := m.importChunk(ctx, batch, params, 0, len(batch), result, &mu)
+		for i := range result.Failed {
+			result.Failed[i].Index += offset
+		}
+		total.NumRecordsImported += result.NumRecordsImported
+		total.Failed = append(total.Failed, result.Failed...)
+
+		if err != nil {
+			fail(offset, err)
+		}
+
+		offset += len(batch)
+		batch, params = nil, nil
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			fail(offset, err)
+			batch, params = nil, nil
+			break
+		}
+
+		event, err := stream.next()
+		if err == io.EOF {
+			break
+		}
+
+		var lerr *ErrMalformedLine
+		if errors.As(err, &lerr) && m.SkipMalformedLines {
+			total.Skipped++
+			continue
+		}
+		var p map[string]interface{}
+		if err == nil {
+			err = m.validateEvent("import", offset+len(batch), event.DistinctID, event.EventName, event.Event)
+			if err == nil {
+				p, err = m.eventToParams(ctx, "import", offset+len(batch), event.DistinctID, event.EventName, event.Event)
+			}
+			if err != nil && m.SkipMalformedLines {
+				total.Skipped++
+				continue
+			}
+		}
+		if err != nil {
+			if len(batch) > 0 {
+				flush()
+			}
+			fail(offset, err)
+			break
+		}
+
+		batch = append(batch, event)
+		params = append(params, p)
+		if len(batch) == batchLimit(m.BatchLimits.Import, MaxImportBatchSize) {
+			if err := flush(); isAuthError(err) {
+				break
+			}
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+
+	if len(errs) > 0 {
+		return total, &ErrBatchFailed{Errors: errs, Offsets: offsets}
+	}
+
+	return total, nil
+}
@@ -0,0 +1,121 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportStream(t *testing.T) {
+	var batches []int
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var events []map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&events)
+		batches = append(batches, len(events))
+
+		LastRequest = r
+		LastPost, _ = json.Marshal(events[len(events)-1])
+		w.WriteHeader(200)
+		fmt.Fprintf(w, `{"code": 200, "num_records_imported": %d, "status": "OK"}`, len(events))
+	}))
+	defer teardown()
+
+	client = NewWithSecret("e3bc4100330c35722740fb8c6f5abddc", "mysecret", ts.URL)
+
+	var lines strings.Builder
+	for i := 0; i < MaxImportBatchSize+1; i++ {
+		fmt.Fprintf(&lines, `{"event": "Signed Up", "properties": {"distinct_id": "13793", "time": %d}}`+"\n", 1456833600+i)
+	}
+	lines.WriteString("\n")
+
+	result, err := client.ImportStream(context.TODO(), strings.NewReader(lines.String()))
+	if err != nil {
+		t.Fatalf("ImportStream returned an error: %v", err)
+	}
+	if result.NumRecordsImported != MaxImportBatchSize+1 {
+		t.Errorf("NumRecordsImported returned %d, want %d", result.NumRecordsImported, MaxImportBatchSize+1)
+	}
+	if want := []int{MaxImportBatchSize, 1}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("ImportStream sent batches of %v events, want %v", batches, want)
+	}
+
+	want := fmt.Sprintf(`{"event":"Signed Up","properties":{"distinct_id":"13793","time":%d,"token":"e3bc4100330c35722740fb8c6f5abddc"}}`, 1456833600+MaxImportBatchSize)
+	if string(LastPost) != want {
+		t.Errorf("Last event returned %s, want %s", LastPost, want)
+	}
+}
+
+func TestImportStreamMalformedLines(t *testing.T) {
+	requests := 0
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var events []map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&events)
+		fmt.Fprintf(w, `{"code": 200, "num_records_imported": %d, "status": "OK"}`, len(events))
+	}))
+	defer teardown()
+
+	lines := `{"event": "Signed Up", "properties": {"distinct_id": "13793", "time": 1456833600}}
+not json
+{"properties": {"distinct_id": "13793", "time": 1456833600}}
+{"event": "Signed Up", "properties": {"distinct_id": "13794", "time": 1456833600}}
+`
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL))
+
+	result, err := client.ImportStream(context.TODO(), strings.NewReader(lines))
+
+	var lerr *ErrMalformedLine
+	if !errors.As(err, &lerr) || lerr.Line != 2 {
+		t.Fatalf("Error should be an *ErrMalformedLine for line 2: %v", err)
+	}
+	if result.NumRecordsImported != 1 || requests != 1 {
+		t.Errorf("ImportStream imported %d events in %d requests, want 1 in 1", result.NumRecordsImported, requests)
+	}
+
+	requests = 0
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL), WithSkipMalformedLines())
+
+	result, err = client.ImportStream(context.TODO(), strings.NewReader(lines))
+	if err != nil {
+		t.Fatalf("ImportStream returned an error: %v", err)
+	}
+	if result.NumRecordsImported != 2 || result.Skipped != 2 || requests != 1 {
+		t.Errorf("ImportStream imported %d events and skipped %d lines in %d requests, want 2, 2 and 1",
+			result.NumRecordsImported, result.Skipped, requests)
+	}
+}
+
+func TestImportStreamLargeIntegers(t *testing.T) {
+	var body []byte
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		fmt.Fprint(w, `{"code": 200, "num_records_imported": 1, "status": "OK"}`)
+	}))
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithSecret("mysecret"), WithBaseURL(ts.URL))
+
+	lines := `{"event": "Ordered", "properties": {"distinct_id": "13793", "order_id": 9007199254740993, "price": 19.99}}` + "\n"
+	if _, err := client.ImportStream(context.TODO(), strings.NewReader(lines)); err != nil {
+		t.Fatalf("ImportStream returned an error: %v", err)
+	}
+
+	want := `[{"event":"Ordered","properties":{"distinct_id":"13793","order_id":9007199254740993,"price":19.99,"token":"e3bc4100330c35722740fb8c6f5abddc"}}]`
+	if string(body) != want {
+		t.Errorf("Post body returned %s, want %s", body, want)
+	}
+
+	_, err := client.ImportStream(context.TODO(), strings.NewReader(`{"event": "Ordered", "properties": {}} {}`))
+	var lerr *ErrMalformedLine
+	if !errors.As(err, &lerr) || lerr.Line != 1 {
+		t.Errorf("Error should be an *ErrMalformedLine for a line with trailing data: %v", err)
+	}
+}
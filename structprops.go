@@ -0,0 +1,121 @@
+package mixpanel
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// StructProperties returns the properties of v, a struct or a pointer to a
+// struct. Fields are named after their `mixpanel` tag, or their `json` tag
+// without one, or else their name, and skipped when tagged "-" or
+// unexported. The tag options are:
+//
+//	omitempty  skips the field when it has its zero value
+//	flatten    sets the fields of a nested struct as properties of v
+//
+// Nested structs are otherwise set as objects, except for time.Time values
+// which are kept as is. Embedded structs are always flattened.
+func StructProperties(v interface{}) (map[string]interface{}, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, fmt.Errorf("mixpanel: can't get the properties of a nil %T", v)
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mixpanel: can't get the properties of %T, which is not a struct", v)
+	}
+
+	props := map[string]interface{}{}
+	structProperties(value, props)
+
+	return props, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func structProperties(value reflect.Value, props map[string]interface{}) {
+	t := value.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("mixpanel")
+		if !ok {
+			tag = field.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		omitEmpty := hasTagOption(opts, "omitempty")
+
+		fieldValue := value.Field(i)
+		if omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+
+		nested, isStruct := structValue(fieldValue)
+		if isStruct && (field.Anonymous || hasTagOption(opts, "flatten")) {
+			structProperties(nested, props)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if isStruct {
+			object := map[string]interface{}{}
+			structProperties(nested, object)
+			props[name] = object
+		} else {
+			props[name] = fieldValue.Interface()
+		}
+	}
+}
+
+// structValue returns the struct held by value, following pointers, unless
+// it is a time.Time.
+func structValue(value reflect.Value) (reflect.Value, bool) {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return value, false
+		}
+		value = value.Elem()
+	}
+
+	return value, value.Kind() == reflect.Struct && value.Type() != timeType
+}
+
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackStruct tracks an event with the properties of props, a struct
+// described by StructProperties.
+func (m *mixpanel) TrackStruct(ctx context.Context, distinctID, eventName string, props interface{}) error {
+	properties, err := StructProperties(props)
+	if err != nil {
+		return err
+	}
+
+	return m.Track(ctx, distinctID, eventName, &Event{Properties: properties})
+}
@@ -0,0 +1,82 @@
+package mixpanel
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type campaign struct {
+	Source string `mixpanel:"utm_source"`
+	Medium string `mixpanel:"utm_medium,omitempty"`
+}
+
+type Device struct {
+	OS string `json:"os"`
+}
+
+type signedUp struct {
+	Device
+
+	Plan      string    `mixpanel:"plan"`
+	Seats     int       `json:"seats,omitempty"`
+	Referrer  string    `mixpanel:"referrer,omitempty"`
+	Campaign  campaign  `mixpanel:"campaign,flatten"`
+	Billing   *campaign `mixpanel:"billing"`
+	Trial     time.Time `mixpanel:"trial_end"`
+	Ignored   string    `mixpanel:"-"`
+	NoTag     bool
+	unexposed string
+}
+
+func TestStructProperties(t *testing.T) {
+	trial := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	props, err := StructProperties(&signedUp{
+		Device:    Device{OS: "linux"},
+		Plan:      "pro",
+		Campaign:  campaign{Source: "newsletter"},
+		Billing:   &campaign{Source: "invoice", Medium: "email"},
+		Trial:     trial,
+		Ignored:   "ignored",
+		NoTag:     true,
+		unexposed: "unexposed",
+	})
+	if err != nil {
+		t.Fatalf("StructProperties returned an error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"os":         "linux",
+		"plan":       "pro",
+		"utm_source": "newsletter",
+		"billing":    map[string]interface{}{"utm_source": "invoice", "utm_medium": "email"},
+		"trial_end":  trial,
+		"NoTag":      true,
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("StructProperties returned %+v, want %+v", props, want)
+	}
+
+	if _, err := StructProperties(map[string]interface{}{}); err == nil {
+		t.Error("StructProperties of a map should return an error")
+	}
+	if _, err := StructProperties((*signedUp)(nil)); err == nil {
+		t.Error("StructProperties of a nil pointer should return an error")
+	}
+}
+
+func TestTrackStruct(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.TrackStruct(context.TODO(), "13793", "Signed Up", signedUp{Plan: "pro", Seats: 3})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"NoTag\":false,\"billing\":null,\"distinct_id\":\"13793\",\"os\":\"\",\"plan\":\"pro\",\"seats\":3,\"token\":\"e3bc4100330c35722740fb8c6f5abddc\",\"trial_end\":-62135596800,\"utm_source\":\"\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
@@ -0,0 +1,36 @@
+package mixpanel
+
+import "sync"
+
+// superProperties holds the properties merged into every event of a client.
+type superProperties struct {
+	mu    sync.RWMutex
+	props map[string]interface{}
+}
+
+// SetSuperProperties sets properties merged into every event tracked or
+// imported afterwards, such as the version of the application. Properties
+// of the events win over super properties with the same names. Calling it
+// again replaces all the super properties, and nil removes them. It is safe
+// to call concurrently with the other methods of the client.
+func (m *mixpanel) SetSuperProperties(props map[string]interface{}) {
+	copied := make(map[string]interface{}, len(props))
+	for key, value := range props {
+		copied[key] = value
+	}
+
+	m.super.mu.Lock()
+	defer m.super.mu.Unlock()
+
+	m.super.props = copied
+}
+
+// addSuperProperties copies the super properties of the client into props.
+func (m *mixpanel) addSuperProperties(props map[string]interface{}) {
+	m.super.mu.RLock()
+	defer m.super.mu.RUnlock()
+
+	for key, value := range m.super.props {
+		props[key] = value
+	}
+}
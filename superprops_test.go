@@ -0,0 +1,63 @@
+package mixpanel
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSuperProperties(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.SetSuperProperties(map[string]interface{}{
+		"App Version": "1.2.0",
+		"Plan":        "Free",
+	})
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{
+			"Plan": "Premium",
+		},
+	})
+
+	want := "{\"event\":\"Signed Up\",\"properties\":{\"App Version\":\"1.2.0\",\"Plan\":\"Premium\",\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+
+	client.SetSuperProperties(nil)
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	want = "{\"event\":\"Signed Up\",\"properties\":{\"distinct_id\":\"13793\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\"}}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v",
+			decodeBody(), want)
+	}
+}
+
+func TestSuperPropertiesConcurrency(t *testing.T) {
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithDryRun())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			client.SetSuperProperties(map[string]interface{}{"Worker": i})
+		}(i)
+		go func() {
+			defer wg.Done()
+			client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+		}()
+	}
+	wg.Wait()
+
+	if n := len(DryRunRequests(client)); n != 10 {
+		t.Errorf("DryRunRequests returned %d requests, want 10", n)
+	}
+}
@@ -0,0 +1,74 @@
+package mixpanel
+
+import (
+	"math"
+	"time"
+)
+
+// isoTimeFormat is the format of the dates mixpanel recognizes in
+// properties, always in UTC.
+const isoTimeFormat = "2006-01-02T15:04:05"
+
+// encodeTimes returns value with the time.Time values it holds, including
+// those of nested objects and lists, encoded as set by WithTimeFormat, and
+// its NaN and infinite numbers replaced by nil with WithNonFiniteAsNull. Maps
+// and lists are copied rather than modified. Other values are kept as is,
+// so that their json.Marshaler implementations are used when sending them.
+func (m *mixpanel) encodeTimes(value interface{}) interface{} {
+	switch value := value.(type) {
+	case time.Time:
+		return m.encodeTime(value)
+	case *time.Time:
+		if value == nil {
+			return value
+		}
+		return m.encodeTime(*value)
+	case float64:
+		if m.NonFiniteAsNull && (math.IsNaN(value) || math.IsInf(value, 0)) {
+			return nil
+		}
+	case float32:
+		if m.NonFiniteAsNull && !finiteNumbers(value) {
+			return nil
+		}
+	case []float64:
+		if m.NonFiniteAsNull && !finiteNumbers(value) {
+			encoded := make([]interface{}, len(value))
+			for i, v := range value {
+				encoded[i] = m.encodeTimes(v)
+			}
+			return encoded
+		}
+	case map[string]interface{}:
+		encoded := make(map[string]interface{}, len(value))
+		for key, v := range value {
+			encoded[key] = m.encodeTimes(v)
+		}
+		return encoded
+	case map[string][]interface{}:
+		encoded := make(map[string][]interface{}, len(value))
+		for key, v := range value {
+			encoded[key] = m.encodeTimes(v).([]interface{})
+		}
+		return encoded
+	case []interface{}:
+		if value == nil {
+			return value
+		}
+		encoded := make([]interface{}, len(value))
+		for i, v := range value {
+			encoded[i] = m.encodeTimes(v)
+		}
+		return encoded
+	}
+
+	return value
+}
+
+func (m *mixpanel) encodeTime(t time.Time) interface{} {
+	if m.TimeFormat == TimeFormatEpoch {
+		return t.Unix()
+	}
+
+	return t.UTC().Format(isoTimeFormat)
+}
@@ -0,0 +1,81 @@
+package mixpanel
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// planName marshals itself as the upper case name of a plan.
+type planName string
+
+func (p planName) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToUpper(string(p)))
+}
+
+func TestTimeProperties(t *testing.T) {
+	pst := time.FixedZone("PST", -8*60*60)
+	renewal := time.Date(2016, 3, 3, 7, 17, 53, 0, pst)
+	trialEnd := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	props := map[string]interface{}{
+		"renewal":   renewal,
+		"trial_end": &trialEnd,
+		"invoices":  []interface{}{renewal},
+		"billing":   map[string]interface{}{"since": trialEnd},
+		"plan":      planName("pro"),
+		"raw":       json.RawMessage(`{"seats":3}`),
+	}
+
+	for _, test := range []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{
+			name: "iso",
+			opts: []Option{WithTimeFormat(TimeFormatISO)},
+			want: "{\"event\":\"Renewed\",\"properties\":{\"billing\":{\"since\":\"2016-03-01T00:00:00\"},\"distinct_id\":\"13793\",\"invoices\":[\"2016-03-03T15:17:53\"],\"plan\":\"PRO\",\"raw\":{\"seats\":3},\"renewal\":\"2016-03-03T15:17:53\",\"token\":\"e3bc4100330c35722740fb8c6f5abddc\",\"trial_end\":\"2016-03-01T00:00:00\"}}",
+		},
+		{
+			name: "epoch",
+			want: "{\"event\":\"Renewed\",\"properties\":{\"billing\":{\"since\":1456790400},\"distinct_id\":\"13793\",\"invoices\":[1457018273],\"plan\":\"PRO\",\"raw\":{\"seats\":3},\"renewal\":1457018273,\"token\":\"e3bc4100330c35722740fb8c6f5abddc\",\"trial_end\":1456790400}}",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			setup()
+			defer teardown()
+
+			client = NewClient("e3bc4100330c35722740fb8c6f5abddc", append([]Option{WithBaseURL(ts.URL)}, test.opts...)...)
+			client.Track(context.TODO(), "13793", "Renewed", &Event{Properties: props})
+
+			if !reflect.DeepEqual(decodeBody(), test.want) {
+				t.Errorf("Post body returned %+v, want %+v", decodeBody(), test.want)
+			}
+		})
+	}
+
+	if _, ok := props["renewal"].(time.Time); !ok {
+		t.Error("the properties of the event were modified")
+	}
+}
+
+func TestTimeProfileProperties(t *testing.T) {
+	setup()
+	defer teardown()
+
+	lastLogin := time.Date(2016, 3, 3, 15, 17, 53, 0, time.UTC)
+	client.SetOnce(context.TODO(), "13793", map[string]interface{}{
+		"$last_login": lastLogin,
+		"plan":        planName("pro"),
+	})
+
+	want := "{\"$distinct_id\":\"13793\",\"$set_once\":{\"$last_login\":1457018273,\"plan\":\"PRO\"},\"$token\":\"e3bc4100330c35722740fb8c6f5abddc\"}"
+
+	if !reflect.DeepEqual(decodeBody(), want) {
+		t.Errorf("Post body returned %+v, want %+v", decodeBody(), want)
+	}
+}
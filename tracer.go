@@ -0,0 +1,71 @@
+package mixpanel
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer traces the requests sent by a client, see WithTracer. The
+// mixpanelotel package implements it with OpenTelemetry.
+type Tracer interface {
+	// StartSpan is called before sending a request carrying the given
+	// number of records to an api, such as "track", "import" or "jql". The
+	// request is sent with the returned context, and end is called once it
+	// completed. The status code is zero when no response was received.
+	StartSpan(ctx context.Context, operation, url string, records int) (_ context.Context, end func(statusCode int, err error))
+}
+
+// nopTracer is the tracer of clients created without WithTracer.
+type nopTracer struct{}
+
+func (nopTracer) StartSpan(ctx context.Context, operation, url string, records int) (context.Context, func(int, error)) {
+	return ctx, func(int, error) {}
+}
+
+// multiTracer calls several tracers, see WithTracer.
+type multiTracer []Tracer
+
+func (t multiTracer) StartSpan(ctx context.Context, operation, url string, records int) (context.Context, func(int, error)) {
+	ends := make([]func(int, error), len(t))
+	for i, tracer := range t {
+		ctx, ends[i] = tracer.StartSpan(ctx, operation, url, records)
+	}
+
+	return ctx, func(statusCode int, err error) {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](statusCode, err)
+		}
+	}
+}
+
+// callbackTracer calls a function once requests completed, see
+// WithSendCallback.
+type callbackTracer func(endpoint string, eventCount int, status int, err error)
+
+func (t callbackTracer) StartSpan(ctx context.Context, operation, url string, records int) (context.Context, func(int, error)) {
+	return ctx, func(statusCode int, err error) {
+		t(operation, records, statusCode, err)
+	}
+}
+
+// countRecords returns the number of events or updates in params.
+func countRecords(params interface{}) int {
+	switch p := params.(type) {
+	case nil:
+		return 0
+	case []map[string]interface{}:
+		return len(p)
+	case []interface{}:
+		return len(p)
+	default:
+		return 1
+	}
+}
+
+// statusCode returns the status code of resp, or zero without a response.
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,136 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type span struct {
+	operation  string
+	records    int
+	statusCode int
+	err        error
+}
+
+type recordingTracer struct {
+	spans []*span
+}
+
+type spanKey struct{}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, operation, url string, records int) (context.Context, func(int, error)) {
+	s := &span{operation: operation, records: records}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, spanKey{}, s), func(statusCode int, err error) {
+		s.statusCode = statusCode
+		s.err = err
+	}
+}
+
+func TestTracer(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error": "", "status": 1}`))
+	}))
+	defer teardown()
+
+	var spanContexts []interface{}
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		spanContexts = append(spanContexts, r.Context().Value(spanKey{}))
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	tracer := &recordingTracer{}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithTracer(tracer), WithHTTPClient(&http.Client{Transport: transport}))
+
+	client.TrackBatch(context.TODO(), []*TrackEvent{
+		{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}},
+		{DistinctID: "13794", EventName: "Signed Up", Event: &Event{}},
+	})
+	client.UpdateUser(context.TODO(), "13793", &Update{Operation: "$set", Properties: map[string]interface{}{}})
+
+	want := []*span{
+		{operation: "track", records: 2, statusCode: 200},
+		{operation: "engage", records: 1, statusCode: 200},
+	}
+	if !reflect.DeepEqual(tracer.spans, want) {
+		t.Errorf("spans returned %+v, want %+v", *tracer.spans[0], *want[0])
+	}
+
+	if len(spanContexts) != 2 || spanContexts[0] != tracer.spans[0] || spanContexts[1] != tracer.spans[1] {
+		t.Errorf("requests should be sent with the context of their span")
+	}
+}
+
+func TestTracerError(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"error": "Invalid API secret", "status": 0}`))
+	}))
+	defer teardown()
+
+	tracer := &recordingTracer{}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithTracer(tracer))
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("%d spans were started, want 1", len(tracer.spans))
+	}
+	if s := tracer.spans[0]; s.statusCode != 401 || !errors.Is(s.err, err) {
+		t.Errorf("span returned %+v, want a 401 ending with %v", s, err)
+	}
+}
+
+func TestMultipleTracers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	first, second := &recordingTracer{}, &recordingTracer{}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithTracer(first), WithTracer(second))
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{})
+
+	if len(first.spans) != 1 || len(second.spans) != 1 {
+		t.Errorf("every tracer should start a span, got %d and %d", len(first.spans), len(second.spans))
+	}
+}
+
+func TestWithSendCallback(t *testing.T) {
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error": "", "status": 1}`))
+	}))
+	defer teardown()
+
+	var sends []*span
+	callback := func(endpoint string, eventCount int, status int, err error) {
+		sends = append(sends, &span{operation: endpoint, records: eventCount, statusCode: status, err: err})
+	}
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithSendCallback(callback), WithSendCallback(nil))
+
+	events := []*TrackEvent{}
+	for i := 0; i < MaxTrackBatchSize+3; i++ {
+		events = append(events, &TrackEvent{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}})
+	}
+	client.TrackBatch(context.TODO(), events)
+	client.UpdateUser(context.TODO(), "13793", &Update{Operation: "$set", Properties: map[string]interface{}{}})
+
+	want := []*span{
+		{operation: "track", records: MaxTrackBatchSize, statusCode: 200},
+		{operation: "track", records: 3, statusCode: 200},
+		{operation: "engage", records: 1, statusCode: 200},
+	}
+	if !reflect.DeepEqual(sends, want) {
+		t.Errorf("callback was called with %d sends, want %d", len(sends), len(want))
+		for _, s := range sends {
+			t.Logf("%+v", *s)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package mixpanel
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// MaxPropertyNameLength is the longest property name accepted by
+	// WithPropertyValidation.
+	MaxPropertyNameLength = 255
+
+	// MaxPropertyDepth is the deepest nesting of objects accepted by
+	// WithPropertyValidation, top level properties being at depth 1.
+	MaxPropertyDepth = 3
+)
+
+// ValidationError is returned by clients created with WithStrictValidation
+// or WithPropertyValidation for events and updates which mixpanel would
+// accept but not be able to use. All clients return it for events with
+// invalid Groups, and for events and updates with NaN or infinite numbers,
+// which can't be encoded as JSON, unless created with WithNonFiniteAsNull.
+type ValidationError struct {
+	// Position of the invalid event in its batch, zero outside of batches
+	Index int
+
+	// Name of the invalid field, such as "distinct_id" or "properties"
+	Field   string
+	Message string
+
+	// Names of the invalid properties, sorted, when Field is "properties"
+	Properties []string
+}
+
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("mixpanel: invalid %s: %s", err.Field, err.Message)
+}
+
+var reservedEventProperties = []string{
+	"$app_build_number", "$app_version_string", "$browser", "$browser_version",
+	"$carrier", "$city", "$country_code", "$current_url", "$device",
+	"$device_id", "$duration", "$initial_referrer", "$initial_referring_domain",
+	"$insert_id", "$latitude", "$lib_version", "$longitude", "$manufacturer",
+	"$model", "$os", "$os_version", "$referrer", "$referring_domain", "$region",
+	"$screen_height", "$screen_width", "$source", "$user_id", "mp_country_code",
+	"mp_lib", "mp_processing_time_ms",
+}
+
+var reservedProfileProperties = []string{
+	"$android_devices", "$avatar", "$city", "$country_code", "$created",
+	"$email", "$first_name", "$geo_source", "$ios_devices", "$last_name",
+	"$latitude", "$longitude", "$name", "$phone", "$region", "$timezone",
+	"$unsubscribed",
+}
+
+// ReservedEventProperties returns the names of the event properties which
+// have a special meaning for mixpanel.
+func ReservedEventProperties() []string {
+	return append([]string(nil), reservedEventProperties...)
+}
+
+// ReservedProfileProperties returns the names of the profile properties
+// which have a special meaning for mixpanel.
+func ReservedProfileProperties() []string {
+	return append([]string(nil), reservedProfileProperties...)
+}
+
+// validateEvent checks the groups of the event at index of a batch, and the
+// rest of the event when strict validation is enabled. Its properties are
+// checked by eventToParams once merged.
+func (m *mixpanel) validateEvent(endpoint string, index int, distinctID, eventName string, e *Event) error {
+	var verr *ValidationError
+	if e != nil {
+		verr = validateGroups(index, e)
+	}
+	switch {
+	case verr != nil:
+	case !m.StrictValidation:
+	case m.Token == "" && (e == nil || e.Token == ""):
+		verr = &ValidationError{Index: index, Field: "token", Message: "is empty"}
+	case distinctID == "" && (e == nil || e.DeviceID == ""):
+		verr = &ValidationError{Index: index, Field: "distinct_id", Message: "is empty"}
+	case eventName == "":
+		verr = &ValidationError{Index: index, Field: "event", Message: "is empty"}
+	}
+
+	if verr == nil {
+		return nil
+	}
+
+	return &MixpanelError{URL: m.endpointURL(endpoint), Err: verr}
+}
+
+// validateGroups checks that the group ids of e are strings or numbers, or
+// lists of them, and don't conflict with its properties.
+func validateGroups(index int, e *Event) *ValidationError {
+	keys := make([]string, 0, len(e.Groups))
+	for key := range e.Groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		id := e.Groups[key]
+		switch {
+		case key == "":
+			return &ValidationError{Index: index, Field: "groups", Message: "group key is empty"}
+		case !isGroupID(id):
+			return &ValidationError{Index: index, Field: "groups", Message: fmt.Sprintf("id of %s is a %T, not a string or a number", key, id)}
+		}
+		if value, ok := e.Properties[key]; ok && !reflect.DeepEqual(value, id) {
+			return &ValidationError{
+				Index:      index,
+				Field:      "groups",
+				Message:    fmt.Sprintf("%s is also a property with another value", key),
+				Properties: []string{key},
+			}
+		}
+	}
+	return nil
+}
+
+// validateNumbers returns an error listing the properties of props which
+// are or hold NaN or infinite numbers, which JSON can't encode.
+func validateNumbers(index int, props map[string]interface{}) *ValidationError {
+	var invalid []string
+	for key, value := range props {
+		if !finiteNumbers(value) {
+			invalid = append(invalid, key)
+		}
+	}
+
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	sort.Strings(invalid)
+	return &ValidationError{
+		Index:      index,
+		Field:      "properties",
+		Message:    "NaN or infinite numbers can't be sent: " + strings.Join(invalid, ", "),
+		Properties: invalid,
+	}
+}
+
+// validateValueNumbers is validateNumbers for the value of any operation,
+// such as the lists of $union or the properties of $set.
+func validateValueNumbers(index int, value interface{}) *ValidationError {
+	if props, ok := value.(map[string]interface{}); ok {
+		return validateNumbers(index, props)
+	}
+	if finiteNumbers(value) {
+		return nil
+	}
+
+	return &ValidationError{Index: index, Field: "properties", Message: "NaN or infinite numbers can't be sent"}
+}
+
+// finiteNumbers reports whether value has no NaN or infinite float, looking
+// through maps and lists.
+func finiteNumbers(value interface{}) bool {
+	switch value := value.(type) {
+	case float64:
+		return !math.IsNaN(value) && !math.IsInf(value, 0)
+	case float32:
+		return finiteNumbers(float64(value))
+	case []float64:
+		for _, v := range value {
+			if !finiteNumbers(v) {
+				return false
+			}
+		}
+	case []interface{}:
+		for _, v := range value {
+			if !finiteNumbers(v) {
+				return false
+			}
+		}
+	case map[string]interface{}:
+		for _, v := range value {
+			if !finiteNumbers(v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isGroupID(id interface{}) bool {
+	switch id := id.(type) {
+	case string, int, int32, int64, uint, uint32, uint64, float64, []string, []int, []int64:
+		return true
+	case []interface{}:
+		for _, id := range id {
+			switch id.(type) {
+			case string, int, int32, int64, uint, uint32, uint64, float64:
+			default:
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// validateEvents checks all the events of a batch when strict or property
+// validation is enabled.
+func (m *mixpanel) validateEvents(endpoint string, events []*TrackEvent) error {
+	for i, event := range events {
+		if err := m.validateEvent(endpoint, i, event.DistinctID, event.EventName, event.Event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProfile checks the numbers of the properties of an update at
+// index of a batch, merged with the properties of ctx as by engageParams,
+// and the properties set on a profile when property validation is enabled.
+func (m *mixpanel) validateProfile(ctx context.Context, index int, operation string, value interface{}) error {
+	props, ok := value.(map[string]interface{})
+	set := ok && (operation == "$set" || operation == "$set_once")
+	if !m.NonFiniteAsNull {
+		verr := validateValueNumbers(index, value)
+		if verr == nil && set {
+			verr = validateNumbers(index, contextProperties(ctx))
+		}
+		if verr != nil {
+			return &MixpanelError{URL: m.endpointURL("engage"), Err: verr}
+		}
+	}
+	if !m.PropertyValidation || !set {
+		return nil
+	}
+
+	if verr := validateProperties(index, props, reservedProfileProperties); verr != nil {
+		return &MixpanelError{URL: m.endpointURL("engage"), Err: verr}
+	}
+	return nil
+}
+
+// validateGroupUpdate checks the numbers of the value of a group update at
+// index of a batch.
+func (m *mixpanel) validateGroupUpdate(index int, value interface{}) error {
+	if m.NonFiniteAsNull {
+		return nil
+	}
+	if verr := validateValueNumbers(index, value); verr != nil {
+		return &MixpanelError{URL: m.endpointURL("groups"), Err: verr}
+	}
+	return nil
+}
+
+// validateProperties returns an error listing the properties of props which
+// are reserved by mixpanel without being in reserved, too long, or too
+// deeply nested.
+func validateProperties(index int, props map[string]interface{}, reserved []string) *ValidationError {
+	var invalid []string
+	collectInvalidProperties(props, reserved, "", 1, &invalid)
+
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	sort.Strings(invalid)
+	return &ValidationError{
+		Index:      index,
+		Field:      "properties",
+		Message:    "reserved, too long or too deeply nested: " + strings.Join(invalid, ", "),
+		Properties: invalid,
+	}
+}
+
+func collectInvalidProperties(props map[string]interface{}, reserved []string, prefix string, depth int, invalid *[]string) {
+	for key, value := range props {
+		name := prefix + key
+
+		switch {
+		case len(key) > MaxPropertyNameLength:
+			*invalid = append(*invalid, name)
+			continue
+		case depth == 1 && isReservedName(key) && !contains(reserved, key):
+			*invalid = append(*invalid, name)
+			continue
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			if depth >= MaxPropertyDepth {
+				*invalid = append(*invalid, name)
+				continue
+			}
+			collectInvalidProperties(nested, reserved, name+".", depth+1, invalid)
+		}
+	}
+}
+
+// isReservedName reports whether a property name is in a namespace reserved
+// by mixpanel.
+func isReservedName(key string) bool {
+	return strings.HasPrefix(key, "$") || strings.HasPrefix(key, "mp_")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
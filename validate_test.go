@@ -0,0 +1,228 @@
+package mixpanel
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrictValidation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := 0
+	hook := WithHook(func(*http.Request, *http.Response, error, int, time.Duration) { requests++ })
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithStrictValidation(), hook)
+
+	var verr *ValidationError
+	if err := client.Track(context.TODO(), "", "Signed Up", &Event{}); !errors.As(err, &verr) || verr.Field != "distinct_id" {
+		t.Errorf("Track without distinct id should fail validation: %v", err)
+	}
+	if err := client.Import(context.TODO(), "13793", "", &Event{}); !errors.As(err, &verr) || verr.Field != "event" {
+		t.Errorf("Import without name should fail validation: %v", err)
+	}
+
+	err := client.TrackBatch(context.TODO(), []*TrackEvent{
+		{DistinctID: "13793", EventName: "Signed Up", Event: &Event{}},
+		{DistinctID: "", EventName: "Signed Up", Event: &Event{}},
+	})
+	if !errors.As(err, &verr) || verr.Index != 1 || verr.Field != "distinct_id" {
+		t.Errorf("TrackBatch should report the invalid event: %v", err)
+	}
+
+	client = NewClient("", WithBaseURL(ts.URL), WithStrictValidation(), hook)
+	if err := client.Track(context.TODO(), "13793", "Signed Up", &Event{}); !errors.As(err, &verr) || verr.Field != "token" {
+		t.Errorf("Track without token should fail validation: %v", err)
+	}
+
+	if requests != 0 {
+		t.Errorf("%d invalid requests were sent, want none", requests)
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithStrictValidation(), hook)
+	if err := client.Track(context.TODO(), "", "Signed Up", &Event{DeviceID: "device-1"}); errors.As(err, &verr) {
+		t.Errorf("Track with a device id should pass validation: %v", err)
+	}
+	requests = 0
+
+	// Anonymous events are sent without strict validation.
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), hook)
+	client.Track(context.TODO(), "", "Signed Up", &Event{})
+	if requests != 1 {
+		t.Errorf("%d anonymous requests were sent, want 1", requests)
+	}
+}
+
+func TestPropertyValidation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithPropertyValidation())
+
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{
+			"$browser":               "Chrome",
+			"$plan":                  "pro",
+			"mp_custom":              1,
+			strings.Repeat("a", 256): true,
+			"nested":                 map[string]interface{}{"level2": map[string]interface{}{"level3": map[string]interface{}{}}},
+			"fine":                   map[string]interface{}{"$anything": 1},
+		},
+	})
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Error should be a *ValidationError: %v", err)
+	}
+	want := []string{"$plan", strings.Repeat("a", 256), "mp_custom", "nested.level2.level3"}
+	if !reflect.DeepEqual(verr.Properties, want) {
+		t.Errorf("invalid properties returned %+v, want %+v", verr.Properties, want)
+	}
+
+	err = client.UpdateUser(context.TODO(), "13793", &Update{
+		Operation:  "$set",
+		Properties: map[string]interface{}{"$email": "bob@example.com", "$browser": "Chrome"},
+	})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"$browser"}) {
+		t.Errorf("$browser is not a profile property: %v", err)
+	}
+
+	if err := client.SetProfile(context.TODO(), "13793", Profile{Name: "Bob"}, nil); errors.As(err, &verr) {
+		t.Errorf("reserved profile properties should be valid: %v", err)
+	}
+}
+
+func TestNonFiniteNumbers(t *testing.T) {
+	setup()
+	defer teardown()
+	LastRequest = nil
+
+	var verr *ValidationError
+	err := client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{"Ratio": math.NaN(), "Scores": []interface{}{1.0, math.Inf(-1)}, "Plan": "Premium"},
+	})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"Ratio", "Scores"}) {
+		t.Errorf("Track with NaN and infinite numbers should fail validation: %v", err)
+	}
+
+	err = client.UpdateUser(context.TODO(), "13793", &Update{
+		Operation:  "$set",
+		Properties: map[string]interface{}{"Ratio": math.Inf(1)},
+	})
+	if !errors.As(err, &verr) || verr.Field != "properties" {
+		t.Errorf("UpdateUser with an infinite number should fail validation: %v", err)
+	}
+
+	client.SetSuperProperties(map[string]interface{}{"Ratio": math.NaN()})
+	err = client.Track(context.TODO(), "13793", "Signed Up", &Event{Properties: map[string]interface{}{"Plan": "Premium"}})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"Ratio"}) {
+		t.Errorf("Track with a NaN super property should fail validation: %v", err)
+	}
+	client.SetSuperProperties(nil)
+
+	ctx := WithContextProperties(context.TODO(), map[string]interface{}{"Score": math.Inf(1)})
+	err = client.Track(ctx, "13793", "Signed Up", &Event{})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"Score"}) {
+		t.Errorf("Track with an infinite context property should fail validation: %v", err)
+	}
+	err = client.UpdateUser(ctx, "13793", &Update{Operation: "$set", Properties: map[string]interface{}{}})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"Score"}) {
+		t.Errorf("UpdateUser with an infinite context property should fail validation: %v", err)
+	}
+
+	if LastRequest != nil {
+		t.Errorf("events and updates with NaN or infinite numbers should not be sent")
+	}
+}
+
+func TestNonFiniteGroupNumbers(t *testing.T) {
+	setup()
+	defer teardown()
+	LastRequest = nil
+
+	var verr *ValidationError
+	err := client.GroupSet(context.TODO(), "company_id", "11", map[string]interface{}{"Ratio": math.NaN(), "Name": "Acme"})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"Ratio"}) {
+		t.Errorf("GroupSet with a NaN number should fail validation: %v", err)
+	}
+
+	err = client.GroupSetOnce(context.TODO(), "company_id", "11", map[string]interface{}{"Ratio": math.Inf(1)})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"Ratio"}) {
+		t.Errorf("GroupSetOnce with an infinite number should fail validation: %v", err)
+	}
+
+	err = client.GroupUnion(context.TODO(), "company_id", "11", map[string][]interface{}{"Scores": {1.0, math.Inf(-1)}})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"Scores"}) {
+		t.Errorf("GroupUnion with an infinite number should fail validation: %v", err)
+	}
+
+	err = client.GroupRemove(context.TODO(), "company_id", "11", map[string]interface{}{"Scores": math.NaN()})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"Scores"}) {
+		t.Errorf("GroupRemove with a NaN number should fail validation: %v", err)
+	}
+
+	err = client.UpdateGroup(context.TODO(), "company_id", "11", &Update{Operation: "$set", Properties: map[string]interface{}{"Ratio": math.NaN()}})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Properties, []string{"Ratio"}) {
+		t.Errorf("UpdateGroup with a NaN number should fail validation: %v", err)
+	}
+
+	err = client.UpdateGroupBatch(context.TODO(), "company_id", []*GroupUpdate{
+		{GroupID: "11", Update: &Update{Operation: "$set", Properties: map[string]interface{}{"Name": "Acme"}}},
+		{GroupID: "12", Update: &Update{Operation: "$set", Properties: map[string]interface{}{"Ratio": math.Inf(1)}}},
+	})
+	if !errors.As(err, &verr) || verr.Index != 1 || !reflect.DeepEqual(verr.Properties, []string{"Ratio"}) {
+		t.Errorf("UpdateGroupBatch with an infinite number should fail validation of the update: %v", err)
+	}
+
+	if _, err := client.EncodeGroupUpdate("company_id", "11", &Update{Operation: "$set", Properties: map[string]interface{}{"Ratio": math.NaN()}}); !errors.As(err, &verr) {
+		t.Errorf("EncodeGroupUpdate with a NaN number should fail validation: %v", err)
+	}
+
+	if LastRequest != nil {
+		t.Errorf("group updates with NaN or infinite numbers should not be sent")
+	}
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithNonFiniteAsNull())
+	client.GroupSet(context.TODO(), "company_id", "11", map[string]interface{}{"Ratio": math.NaN()})
+
+	want := `{"$group_id":"11","$group_key":"company_id","$set":{"Ratio":null},"$token":"e3bc4100330c35722740fb8c6f5abddc"}`
+	if got := decodeBody(); got != want {
+		t.Errorf("Post body returned %+v, want %+v", got, want)
+	}
+}
+
+func TestWithNonFiniteAsNull(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client = NewClient("e3bc4100330c35722740fb8c6f5abddc", WithBaseURL(ts.URL), WithNonFiniteAsNull())
+
+	client.Track(context.TODO(), "13793", "Signed Up", &Event{
+		Properties: map[string]interface{}{
+			"Ratio":  math.NaN(),
+			"Scores": []float64{1, math.Inf(1)},
+			"Stats":  map[string]interface{}{"Max": math.Inf(-1), "Min": 0.5},
+		},
+	})
+
+	want := `{"event":"Signed Up","properties":{"Ratio":null,"Scores":[1,null],"Stats":{"Max":null,"Min":0.5},"distinct_id":"13793","token":"e3bc4100330c35722740fb8c6f5abddc"}}`
+	if got := decodeBody(); got != want {
+		t.Errorf("Post body returned %+v, want %+v", got, want)
+	}
+
+	client.UpdateUser(context.TODO(), "13793", &Update{
+		Operation:  "$set",
+		Properties: map[string]interface{}{"Ratio": math.Inf(1)},
+	})
+
+	want = `{"$distinct_id":"13793","$set":{"Ratio":null},"$token":"e3bc4100330c35722740fb8c6f5abddc"}`
+	if got := decodeBody(); got != want {
+		t.Errorf("Post body returned %+v, want %+v", got, want)
+	}
+}